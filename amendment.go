@@ -0,0 +1,499 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== CHANGE REQUEST RELATED FUNCTION'S START HERE ================================================================
+
+//amendable is a budget or schedule field that can only be changed through a change request once its entity is locked
+type amendable struct {
+	get func() string
+	set func(string) error
+}
+
+func floatField(f *float64) amendable {
+	return amendable{
+		get: func() string { return strconv.FormatFloat(*f, 'f', -1, 64) },
+		set: func(value string) error {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 {
+				return errors.New("'" + value + "' is not a valid amount")
+			}
+			*f = parsed
+			return nil
+		},
+	}
+}
+
+func dateField(s *string) amendable {
+	return amendable{
+		get: func() string { return *s },
+		set: func(value string) error {
+			_, err := parseDate(value)
+			if err != nil {
+				return err
+			}
+			*s = value
+			return nil
+		},
+	}
+}
+
+func projectAmendable(project *Project) map[string]amendable {
+	return map[string]amendable{
+		"fundGoal":      floatField(&project.FundGoal),
+		"projectBudget": floatField(&project.ProjectBudget),
+		"startDate":     dateField(&project.StartDate),
+		"endDate":       dateField(&project.EndDate),
+	}
+}
+
+func milestoneAmendable(milestone *Milestone) map[string]amendable {
+	return map[string]amendable{
+		"milestoneBudget": floatField(&milestone.MilBudget),
+		"startDate":       dateField(&milestone.StartDate),
+		"endDate":         dateField(&milestone.EndDate),
+	}
+}
+
+func activityAmendable(activity *Activity) map[string]amendable {
+	return map[string]amendable{
+		"activityBudget": floatField(&activity.ActivityBudget),
+		"startDate":      dateField(&activity.StartDate),
+		"endDate":        dateField(&activity.EndDate),
+	}
+}
+
+//amendableValues takes a snapshot of the amendable fields
+func amendableValues(fields map[string]amendable) map[string]string {
+	values := map[string]string{}
+	for name, field := range fields {
+		values[name] = field.get()
+	}
+	return values
+}
+
+//checkAmendments refuses direct changes of amendable fields on a locked entity
+func checkAmendments(locked bool, before map[string]string, fields map[string]amendable) error {
+	if !locked {
+		return nil
+	}
+	var changed []string
+	for name, field := range fields {
+		if field.get() != before[name] {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	return errors.New("Budget and dates are locked once approved or funded, " + strings.Join(changed, ", ") + " can only be changed through a change request")
+}
+
+//isProjectLocked - budgets and dates of approved or funded projects are amended through change requests
+func isProjectLocked(project Project) bool {
+	return project.IsApproved || project.FundRaised > 0
+}
+
+func isMilestoneLocked(project Project, milestone Milestone) bool {
+	return isProjectLocked(project) || milestone.IsApproved || milestone.MilFundAllocated > 0
+}
+
+func isActivityLocked(project Project, activity Activity) bool {
+	return isProjectLocked(project) || activity.IsApproved || activity.FundAllocated > 0
+}
+
+//checkStructureLocked - milestones and activities of locked entities can neither be added nor deleted,
+//change requests only amend budgets and dates
+func checkStructureLocked(locked bool, entity string) error {
+	if !locked {
+		return nil
+	}
+	return errors.New(entity + " is locked once approved or funded, milestones and activities can no longer be added or deleted")
+}
+
+// ============================================================================================================================
+// Get Change Request - get the change request from ledger
+// ============================================================================================================================
+func getChangeRequest(stub shim.ChaincodeStubInterface, id string) (ChangeRequest, error) {
+	var change ChangeRequest
+	changeAsBytes, err := stub.GetState(documentKey("ChangeRequest", id)) //getState retreives a key/value from the ledger
+	if err != nil {                                                       //this seems to always succeed, even if key didn't exist
+		return change, errors.New("Failed to get change request - " + id)
+	}
+	unmarshalDocument(changeAsBytes, &change) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(change.ChangeID) == 0 { //test if change request is actually here or just nil
+		return change, errors.New("change request does not exist - " + id)
+	}
+
+	return change, nil
+}
+
+//applyChange writes the proposed values, the entity must still hold the values the change was proposed against
+func applyChange(stub shim.ChaincodeStubInterface, change ChangeRequest, actor string, txTime string) error {
+	project, err := getProject(stub, change.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]amendable
+	var store func() error
+	var milestone Milestone
+	var activity Activity
+	if change.EntityType == "project" {
+		fields = projectAmendable(&project)
+		store = func() error {
+			err := validateSchedule(project.StartDate, project.EndDate)
+			if err != nil {
+				return err
+			}
+			milestones, err := getMilestonesByProject(stub, project.ProjectID)
+			if err != nil {
+				return err
+			}
+			for i := range milestones {
+				err = validateWithin(milestones[i].StartDate, milestones[i].EndDate, project.StartDate, project.EndDate, "project")
+				if err != nil {
+					return errors.New("Milestone " + milestones[i].MilestoneID + ": " + err.Error())
+				}
+			}
+			project.setUpdated(actor, txTime)
+			projectAsBytes, _ := json.Marshal(project)
+			return stub.PutState(project.ProjectID, projectAsBytes)
+		}
+	} else if change.EntityType == "milestone" {
+		milestone, err = getMilestone(stub, change.EntityID)
+		if err != nil {
+			return err
+		}
+		fields = milestoneAmendable(&milestone)
+		store = func() error {
+			err := validateWithin(milestone.StartDate, milestone.EndDate, project.StartDate, project.EndDate, "project")
+			if err != nil {
+				return err
+			}
+			activities, err := getActivitiesByMilestone(stub, milestone.MilestoneID)
+			if err != nil {
+				return err
+			}
+			for i := range activities {
+				err = validateWithin(activities[i].StartDate, activities[i].EndDate, milestone.StartDate, milestone.EndDate, "milestone")
+				if err != nil {
+					return errors.New("Activity " + activities[i].ActivityID + ": " + err.Error())
+				}
+			}
+			milestone.setUpdated(actor, txTime)
+			milestoneAsBytes, _ := json.Marshal(milestone)
+			return stub.PutState(milestone.MilestoneID, milestoneAsBytes)
+		}
+	} else {
+		activity, err = getActivity(stub, change.EntityID)
+		if err != nil {
+			return err
+		}
+		milestone, err = getMilestone(stub, activity.MilestoneID)
+		if err != nil {
+			return err
+		}
+		fields = activityAmendable(&activity)
+		store = func() error {
+			err := validateWithin(activity.StartDate, activity.EndDate, milestone.StartDate, milestone.EndDate, "milestone")
+			if err != nil {
+				return err
+			}
+			activity.setUpdated(actor, txTime)
+			activityAsBytes, _ := json.Marshal(activity)
+			return stub.PutState(activity.ActivityID, activityAsBytes)
+		}
+	}
+
+	for _, fieldChange := range change.Changes {
+		field := fields[fieldChange.Field]
+		if field.get() != fieldChange.OldValue {
+			return errors.New(change.EntityID + " " + fieldChange.Field + " changed to " + field.get() + " after the change request was proposed")
+		}
+		err = field.set(fieldChange.NewValue)
+		if err != nil {
+			return err
+		}
+	}
+	return store()
+}
+
+// ============================================================================================================================
+// proposeChange() - propose new budget or schedule values for a project, milestone or activity
+//
+// Only members of the project can propose. Any foundation reviewer outside of the project decides on it, the change
+// is applied once reviewApprovals of the configuration have approved it.
+//
+// Inputs - Array of strings
+//              0                   ,    1     ,         2          ,       3
+//  "project"|"milestone"|"activity" , entityId , changes JSON object , justification
+//
+// Amendable fields - project: fundGoal, projectBudget, startDate, endDate
+//                    milestone: milestoneBudget, startDate, endDate
+//                    activity: activityBudget, startDate, endDate
+// e.g. {"endDate":"2027-06-30","projectBudget":"125000"}
+// ============================================================================================================================
+func proposeChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - propose change")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var change ChangeRequest
+	change.EntityType = args[0]
+	change.EntityID = args[1]
+
+	var fields map[string]amendable
+	if change.EntityType == "project" {
+		project, err := getProject(stub, change.EntityID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		change.ProjectID = project.ProjectID
+		fields = projectAmendable(&project)
+	} else if change.EntityType == "milestone" {
+		milestone, err := getMilestone(stub, change.EntityID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		change.ProjectID = milestone.ProjectID
+		fields = milestoneAmendable(&milestone)
+	} else if change.EntityType == "activity" {
+		activity, err := getActivity(stub, change.EntityID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		change.ProjectID = activity.ProjectID
+		fields = activityAmendable(&activity)
+	} else {
+		return shim.Error("Unknown entity type '" + change.EntityType + "'. Expecting project, milestone or activity")
+	}
+
+	project, err := getProject(stub, change.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var proposed map[string]string
+	err = json.Unmarshal([]byte(args[2]), &proposed)
+	if err != nil || len(proposed) == 0 {
+		return shim.Error("Changes must be a non empty JSON object of field names and new values")
+	}
+	names := make([]string, 0, len(proposed))
+	for name := range proposed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			return shim.Error("Field " + name + " of a " + change.EntityType + " cannot be amended")
+		}
+		oldValue := field.get()
+		err = field.set(proposed[name])
+		if err != nil {
+			return shim.Error(name + ": " + err.Error())
+		}
+		change.Changes = append(change.Changes, FieldChange{Field: name, OldValue: oldValue, NewValue: field.get()})
+	}
+
+	//the proposer does not pick the approvers, any reviewer outside of the project decides
+	config, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	change.RequiredApprovals = config.ReviewApprovals
+
+	change.ObjectType = "ChangeRequest"
+	change.SchemaVersion = currentSchemaVersion
+	change.ChangeID = "CR-" + stub.GetTxID()
+	change.Justification = args[3]
+	change.Status = "Pending"
+	change.ProposedBy = callerID
+	change.ProposedAt = txTime
+	change.setCreated(callerID, txTime)
+
+	//store change request
+	changeAsBytes, _ := json.Marshal(change) //convert to array of bytes
+	err = stub.PutState(documentKey("ChangeRequest", change.ChangeID), changeAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - propose change")
+	return shim.Success([]byte(change.ChangeID))
+}
+
+// ============================================================================================================================
+// decideChange() - a foundation reviewer outside of the project approves or rejects a pending change request,
+// the last required approval applies the change
+//
+// Inputs - Array of strings
+//      0    ,            1           ,    2
+//  changeId , "Approved"|"Rejected"  , remarks
+// ============================================================================================================================
+func decideChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - decide change")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if args[1] != "Approved" && args[1] != "Rejected" {
+		return shim.Error("Verdict must be Approved or Rejected")
+	}
+
+	change, err := getChangeRequest(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if change.Status != "Pending" {
+		return shim.Error("Change request " + change.ChangeID + " is " + change.Status)
+	}
+	if !isReviewer(stub, callerID) {
+		return shim.Error("Only foundation reviewers can decide on change requests")
+	}
+	if callerID == change.ProposedBy {
+		return shim.Error("The proposer cannot decide on change request " + change.ChangeID)
+	}
+	project, err := getProject(stub, change.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if checkProjectMember(stub, project, callerID) == nil {
+		return shim.Error("Reviewers cannot decide on changes of their own projects")
+	}
+	for _, decision := range change.Decisions {
+		if decision.Reviewer == callerID {
+			return shim.Error(callerID + " has already decided on change request " + change.ChangeID)
+		}
+	}
+
+	change.Decisions = append(change.Decisions, ApprovalDecision{Reviewer: callerID, Verdict: args[1], Remarks: args[2], DecidedAt: txTime})
+
+	approved := 0
+	for _, decision := range change.Decisions {
+		if decision.Verdict == "Approved" {
+			approved++
+		}
+	}
+	if args[1] == "Rejected" {
+		change.Status = "Rejected"
+	} else if approved >= change.RequiredApprovals {
+		err = applyChange(stub, change, callerID, txTime)
+		if err != nil {
+			return shim.Error("Change request " + change.ChangeID + " cannot be applied: " + err.Error())
+		}
+		change.Status = "Applied"
+		change.AppliedAt = txTime
+	}
+
+	//store change request
+	change.setUpdated(callerID, txTime)
+	changeAsBytes, _ := json.Marshal(change) //convert to array of bytes
+	err = stub.PutState(documentKey("ChangeRequest", change.ChangeID), changeAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - decide change")
+	return shim.Success(changeAsBytes)
+}
+
+// ============================================================================================================================
+// getChangeRequests() - every change request of a project with old and new values, oldest first
+//
+// Inputs - Array of strings
+//      0
+//  projectId
+// ============================================================================================================================
+func getChangeRequests(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get change requests")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkCanViewProject(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var changes []ChangeRequest
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"ChangeRequest\",\"projectId\":\"%s\"}}", args[0])
+	changesAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	json.Unmarshal(changesAsBytes, &changes) //un stringify it aka JSON.parse()
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ProposedAt < changes[j].ProposedAt })
+
+	changesAsBytes, _ = json.Marshal(changes) //convert to array of bytes
+
+	log.Println("- end - get change requests")
+	return shim.Success(changesAsBytes)
+}
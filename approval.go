@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== APPROVAL RELATED FUNCTION'S START HERE ======================================================================
+
+//approvalRequestID is the id of a review round of a project, see documentKey for its ledger key
+func approvalRequestID(projectID string, round int) string {
+	return fmt.Sprintf("%s-APPROVAL-%d", projectID, round)
+}
+
+// ============================================================================================================================
+// Get Approval Request - get a review round of a project from ledger
+// ============================================================================================================================
+func getApprovalRequest(stub shim.ChaincodeStubInterface, id string) (ApprovalRequest, error) {
+	var request ApprovalRequest
+	requestAsBytes, err := stub.GetState(documentKey("ApprovalRequest", id)) //getState retreives a key/value from the ledger
+	if err != nil {                                                          //this seems to always succeed, even if key didn't exist
+		return request, errors.New("Failed to get approval request - " + id)
+	}
+	unmarshalDocument(requestAsBytes, &request) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(request.RequestID) == 0 { //test if approval request is actually here or just nil
+		return request, errors.New("approval request does not exist - " + id)
+	}
+
+	return request, nil
+}
+
+//getCurrentApprovalRequest - the latest review round of a project
+func getCurrentApprovalRequest(stub shim.ChaincodeStubInterface, project Project) (ApprovalRequest, error) {
+	if project.ApprovalRound == 0 {
+		return ApprovalRequest{}, errors.New("Project " + project.ProjectID + " was never submitted for review")
+	}
+	return getApprovalRequest(stub, approvalRequestID(project.ProjectID, project.ApprovalRound))
+}
+
+//isReviewer tells whether the id belongs to a foundation user with the reviewer role
+func isReviewer(stub shim.ChaincodeStubInterface, id string) bool {
+	user, err := getPrivateUser(stub, id)
+	return err == nil && user.Role == "reviewer"
+}
+
+// ============================================================================================================================
+// submitProjectForReview() - start a new review round, only members of the project can submit
+//
+// Inputs - Array of strings
+//      0
+//  projectId
+// ============================================================================================================================
+func submitProjectForReview(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - submit project for review")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if current, err := getCurrentApprovalRequest(stub, project); err == nil && current.Status != "Rejected" {
+		return shim.Error("Project " + project.ProjectID + " is already " + current.Status + " in review round " + strconv.Itoa(current.Round))
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var request ApprovalRequest
+	request.ObjectType = "ApprovalRequest"
+	request.SchemaVersion = currentSchemaVersion
+	request.Round = project.ApprovalRound + 1
+	request.RequestID = approvalRequestID(project.ProjectID, request.Round)
+	request.ProjectID = project.ProjectID
+	request.RequiredApprovals = config.ReviewApprovals
+	request.Status = "Pending"
+	request.SubmittedBy = callerID
+	request.SubmittedAt = txTime
+	request.setCreated(callerID, txTime)
+
+	project.ApprovalRound = request.Round
+	project.IsApproved = false
+	project.IsPublished = false
+
+	//store approval request
+	requestAsBytes, _ := json.Marshal(request) //convert to array of bytes
+	err = stub.PutState(documentKey("ApprovalRequest", request.RequestID), requestAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//store project
+	project.setUpdated(callerID, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	err = stub.PutState(project.ProjectID, projectAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - submit project for review")
+	return shim.Success([]byte(request.RequestID))
+}
+
+// ============================================================================================================================
+// reviewProject() - a foundation reviewer approves or rejects the pending review round of a project
+//
+// Reviewers cannot review projects they are a member of and decide once per round. A single rejection closes the
+// round, the project has to be submitted again.
+//
+// Inputs - Array of strings
+//      0     ,            1           ,    2
+//  projectId , "Approved"|"Rejected"  , remarks
+// ============================================================================================================================
+func reviewProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - review project")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if args[1] != "Approved" && args[1] != "Rejected" {
+		return shim.Error("Verdict must be Approved or Rejected")
+	}
+
+	if !isReviewer(stub, callerID) {
+		return shim.Error("Only foundation reviewers can review projects")
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+	if checkProjectMember(stub, project, callerID) == nil {
+		return shim.Error("Reviewers cannot review their own projects")
+	}
+
+	request, err := getCurrentApprovalRequest(stub, project)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if request.Status != "Pending" {
+		return shim.Error("Review round " + request.RequestID + " is " + request.Status)
+	}
+	for _, decision := range request.Decisions {
+		if decision.Reviewer == callerID {
+			return shim.Error(callerID + " has already reviewed " + request.RequestID)
+		}
+	}
+
+	request.Decisions = append(request.Decisions, ApprovalDecision{Reviewer: callerID, Verdict: args[1], Remarks: args[2], DecidedAt: txTime})
+
+	approved := 0
+	for _, decision := range request.Decisions {
+		if decision.Verdict == "Approved" {
+			approved++
+		}
+	}
+	if args[1] == "Rejected" {
+		request.Status = "Rejected"
+	} else if approved >= request.RequiredApprovals {
+		request.Status = "Approved"
+	}
+	project.IsApproved = request.Status == "Approved"
+	project.Remarks = args[2]
+
+	//store approval request
+	request.setUpdated(callerID, txTime)
+	requestAsBytes, _ := json.Marshal(request) //convert to array of bytes
+	err = stub.PutState(documentKey("ApprovalRequest", request.RequestID), requestAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//store project
+	project.setUpdated(callerID, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	err = stub.PutState(project.ProjectID, projectAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - review project")
+	return shim.Success(requestAsBytes)
+}
+
+// ============================================================================================================================
+// publishProject() - publish a project once its current review round collected the required approvals
+//
+// Inputs - Array of strings
+//      0
+//  projectId
+// ============================================================================================================================
+func publishProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - publish project")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil && !isCallerAdmin(stub, callerID) {
+		return shim.Error(err.Error())
+	}
+
+	request, err := getCurrentApprovalRequest(stub, project)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if request.Status != "Approved" {
+		return shim.Error("Project " + project.ProjectID + " cannot be published, review round " + request.RequestID + " is " + request.Status)
+	}
+
+	project.IsPublished = true
+
+	//store project
+	project.setUpdated(callerID, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	err = stub.PutState(project.ProjectID, projectAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - publish project")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// getApprovalTrail() - all review rounds of a project with every decision, oldest first
+//
+// Inputs - Array of strings
+//      0
+//  projectId
+// ============================================================================================================================
+func getApprovalTrail(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get approval trail")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkCanViewProject(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var requests []ApprovalRequest
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"ApprovalRequest\",\"projectId\":\"%s\"}}", args[0])
+	requestsAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	json.Unmarshal(requestsAsBytes, &requests) //un stringify it aka JSON.parse()
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Round < requests[j].Round })
+
+	trailAsBytes, _ := json.Marshal(requests) //convert to array of bytes
+
+	log.Println("- end - get approval trail")
+	return shim.Success(trailAsBytes)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== BATCH RELATED FUNCTION'S START HERE ===============================================================
+
+//batchOperations are the invokes a batch may contain, they only read through GetState and equality queries
+var batchOperations = map[string]func(shim.ChaincodeStubInterface, []string) pb.Response{
+	"addProject":                addProject,
+	"updateProject":             updateProject,
+	"addMilestone":              addMilestone,
+	"updateMilestone":           updateMilestone,
+	"addActivity":               addActivity,
+	"updateActivity":            updateActivity,
+	"fundAllocateManually":      fundAllocateManually,
+	"balancedfundAllocate":      balancedfundAllocate,
+	"cloneProject":              cloneProject,
+	"createProjectFromTemplate": createProjectFromTemplate,
+}
+
+//batchStub buffers the writes of a batch so that later operations read the writes of earlier ones,
+//the ledger itself only sees them once every operation succeeded
+type batchStub struct {
+	shim.ChaincodeStubInterface
+	writes map[string][]byte // nil marks a deleted key
+	keys   []string          // keys in the order they were first written
+	event  *pb.ChaincodeEvent
+}
+
+func newBatchStub(stub shim.ChaincodeStubInterface) *batchStub {
+	return &batchStub{ChaincodeStubInterface: stub, writes: map[string][]byte{}}
+}
+
+//GetState returns the buffered value of the key, the ledger value otherwise
+func (s *batchStub) GetState(key string) ([]byte, error) {
+	if value, written := s.writes[key]; written {
+		return value, nil
+	}
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+//PutState buffers the value
+func (s *batchStub) PutState(key string, value []byte) error {
+	if len(key) == 0 {
+		return errors.New("key must not be an empty string")
+	}
+	if _, written := s.writes[key]; !written {
+		s.keys = append(s.keys, key)
+	}
+	s.writes[key] = value
+	return nil
+}
+
+//DelState buffers the delete
+func (s *batchStub) DelState(key string) error {
+	if _, written := s.writes[key]; !written {
+		s.keys = append(s.keys, key)
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+//SetEvent keeps the last event, a transaction carries only one
+func (s *batchStub) SetEvent(name string, payload []byte) error {
+	if len(name) == 0 {
+		return errors.New("event name can not be nil string")
+	}
+	s.event = &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
+//GetQueryResult runs the query against the ledger and lays the buffered writes over the results,
+//only selectors of plain field values are supported
+func (s *batchStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	selector, err := parseBatchSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := s.ChaincodeStubInterface.GetQueryResult(query)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []*queryresult.KV
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if _, written := s.writes[queryResponse.Key]; !written {
+			results = append(results, queryResponse)
+		}
+	}
+	for _, key := range s.keys {
+		value := s.writes[key]
+		if value == nil || !matchesSelector(selector, value) {
+			continue
+		}
+		results = append(results, &queryresult.KV{Key: key, Value: value})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key < results[j].Key })
+	return &batchIterator{results: results}, nil
+}
+
+//child starts an operation on top of the batch, its writes are dropped when the operation fails
+func (s *batchStub) child() *batchStub {
+	return newBatchStub(s)
+}
+
+//merge takes over the writes of a successful operation
+func (s *batchStub) merge(child *batchStub) {
+	for _, key := range child.keys {
+		if _, written := s.writes[key]; !written {
+			s.keys = append(s.keys, key)
+		}
+		s.writes[key] = child.writes[key]
+	}
+	if child.event != nil {
+		s.event = child.event
+	}
+}
+
+//flush writes the buffered changes to the ledger
+func (s *batchStub) flush() error {
+	for _, key := range s.keys {
+		var err error
+		if value := s.writes[key]; value == nil {
+			err = s.ChaincodeStubInterface.DelState(key)
+		} else {
+			err = s.ChaincodeStubInterface.PutState(key, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.event != nil {
+		return s.ChaincodeStubInterface.SetEvent(s.event.EventName, s.event.Payload)
+	}
+	return nil
+}
+
+//batchIterator iterates over query results held in memory
+type batchIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+func (it *batchIterator) HasNext() bool {
+	return it.next < len(it.results)
+}
+
+func (it *batchIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more query results")
+	}
+	it.next++
+	return it.results[it.next-1], nil
+}
+
+func (it *batchIterator) Close() error {
+	return nil
+}
+
+//parseBatchSelector reads a {"selector":{...}} query made of plain field values only
+func parseBatchSelector(query string) (map[string]interface{}, error) {
+	var parsed map[string]map[string]interface{}
+	err := json.Unmarshal([]byte(query), &parsed)
+	if err != nil || len(parsed) != 1 || parsed["selector"] == nil {
+		return nil, errors.New("query is not supported inside a batch - " + query)
+	}
+	for field, value := range parsed["selector"] {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, errors.New("query is not supported inside a batch - " + query)
+		}
+		if strings.HasPrefix(field, "$") {
+			return nil, errors.New("query is not supported inside a batch - " + query)
+		}
+	}
+	return parsed["selector"], nil
+}
+
+//matchesSelector tells whether a buffered document has every field value of the selector
+func matchesSelector(selector map[string]interface{}, value []byte) bool {
+	var doc map[string]interface{}
+	if json.Unmarshal(value, &doc) != nil {
+		return false
+	}
+	for field, expected := range selector {
+		var actual interface{} = doc
+		for _, part := range strings.Split(field, ".") {
+			object, ok := actual.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			actual = object[part]
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+//resolveBatchReferences replaces the arguments named in the refs of an operation by the id the earlier operation
+//with that index returned, so that a batch can add activities to a milestone whose id is generated within the same batch
+func resolveBatchReferences(operation BatchOperation, index int, ids map[int]string) ([]string, error) {
+	resolved := make([]string, len(operation.Args))
+	copy(resolved, operation.Args)
+	for position, ref := range operation.Refs {
+		argIndex, err := strconv.Atoi(position)
+		if err != nil || argIndex < 0 || argIndex >= len(resolved) {
+			return nil, errors.New("Reference " + position + " names no argument of the operation")
+		}
+		id, ok := ids[ref]
+		if ref >= index || !ok || len(id) == 0 {
+			return nil, errors.New("Argument " + position + " refers to operation " + strconv.Itoa(ref) + ", which returned no id before")
+		}
+		resolved[argIndex] = id
+	}
+	return resolved, nil
+}
+
+//batchPayload keeps a JSON payload as is and quotes any other
+func batchPayload(payload []byte) json.RawMessage {
+	if len(payload) == 0 {
+		return nil
+	}
+	if json.Valid(payload) {
+		return json.RawMessage(payload)
+	}
+	quoted, _ := json.Marshal(string(payload))
+	return json.RawMessage(quoted)
+}
+
+// ============================================================================================================================
+// batch() - apply an ordered list of operations in one transaction, all of them or none
+//
+// Every operation sees the writes of the operations before it. When any operation fails nothing is written and the
+// error lists every failed operation; a failed operation leaves no writes behind for the ones after it. The refs of an
+// operation replace arguments by the id returned by an earlier operation, e.g. {"1":0} puts the milestone id generated
+// by operation 0 into argument 1.
+//
+// Inputs - Array of strings
+//                                        0
+//  [{"function":"addMilestone","args":[...]},{"function":"addActivity","args":[...],"refs":{"1":0}},...]
+//
+// Returns - [{"index":0,"function":"addMilestone","payload":...},...]
+// ============================================================================================================================
+func batch(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - batch")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//the operations are sanitized one by one, the list itself may exceed maxArgLength
+	var operations []BatchOperation
+	err = json.Unmarshal([]byte(args[0]), &operations)
+	if err != nil {
+		return shim.Error("Expecting a JSON array of operations")
+	}
+	if len(operations) == 0 {
+		return shim.Error("A batch needs at least one operation")
+	}
+	config, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(operations) > config.MaxBatchOperations {
+		return shim.Error("A batch takes at most " + strconv.Itoa(config.MaxBatchOperations) + " operations")
+	}
+
+	overlay := newBatchStub(stub)
+	results := []BatchResult{}
+	failures := []BatchResult{}
+	ids := map[int]string{} // ids returned by the operations, for the refs of later operations
+	for i, operation := range operations {
+		result := BatchResult{Index: i, Function: operation.Function}
+		handler, ok := batchOperations[operation.Function]
+		if !ok {
+			result.Error = "Function " + operation.Function + " can not be batched"
+			failures = append(failures, result)
+			continue
+		}
+		opArgs, err := resolveBatchReferences(operation, i, ids)
+		if err != nil {
+			result.Error = err.Error()
+			failures = append(failures, result)
+			continue
+		}
+		opStub := overlay.child()
+		response := handler(opStub, opArgs)
+		if response.Status >= shim.ERRORTHRESHOLD {
+			result.Error = response.Message
+			failures = append(failures, result)
+			continue
+		}
+		overlay.merge(opStub)
+		ids[i] = string(response.Payload)
+		result.Payload = batchPayload(response.Payload)
+		results = append(results, result)
+	}
+	if len(failures) > 0 {
+		failuresAsBytes, _ := json.Marshal(failures) //convert to array of bytes
+		return shim.Error("Batch rejected, nothing was written: " + string(failuresAsBytes))
+	}
+
+	err = overlay.flush()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsAsBytes, _ := json.Marshal(results) //convert to array of bytes
+
+	log.Println("- end - batch")
+	return shim.Success(resultsAsBytes)
+}
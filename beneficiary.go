@@ -0,0 +1,449 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//beneficiaryCollection holds the personal details of beneficiaries, see collections_config.json
+const beneficiaryCollection = "collectionBeneficiaryPrivate"
+
+//beneficiarySaltCollection keeps the salt for good, the personal details expire after blockToLive blocks
+const beneficiarySaltCollection = "collectionBeneficiarySalt"
+
+//beneficiarySaltKey is the private key of the salt every beneficiary identifier is hashed with
+var beneficiarySaltKey = reservedKey("beneficiarySalt")
+
+//registrarRole is granted by admins to the users who register beneficiaries and record their consent
+const registrarRole = "registrar"
+
+//=============== BENEFICIARY RELATED FUNCTION'S START HERE ===============================================================
+
+// ============================================================================================================================
+// Get Beneficiary - get the public beneficiary record from ledger
+// ============================================================================================================================
+func getBeneficiary(stub shim.ChaincodeStubInterface, id string) (Beneficiary, error) {
+	var beneficiary Beneficiary
+	beneficiaryAsBytes, err := stub.GetState(id) //getState retreives a key/value from the ledger
+	if err != nil {                              //this seems to always succeed, even if key didn't exist
+		return beneficiary, errors.New("Failed to get beneficiary - " + id)
+	}
+	unmarshalDocument(beneficiaryAsBytes, &beneficiary) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(beneficiary.BeneficiaryID) == 0 { //test if beneficiary is actually here or just nil
+		return beneficiary, errors.New("beneficiary does not exist - " + id)
+	}
+
+	return beneficiary, nil
+}
+
+//beneficiaryID derives the public identifier from the salted personal identifier
+func beneficiaryID(salt []byte, identifier string) string {
+	digest := sha256.Sum256(append(append(salt, ':'), []byte(strings.ToLower(strings.TrimSpace(identifier)))...))
+	return "BEN-" + hex.EncodeToString(digest[:])
+}
+
+//getBeneficiarySalt reads the private salt an admin seeded with seedBeneficiarySalt
+func getBeneficiarySalt(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	salt, err := stub.GetPrivateData(beneficiarySaltCollection, beneficiarySaltKey)
+	if err != nil {
+		return nil, errors.New("Failed to read the beneficiary salt")
+	}
+	if len(salt) == 0 {
+		return nil, errors.New("No beneficiary salt stored yet, an admin has to seed it with seedBeneficiarySalt")
+	}
+	return salt, nil
+}
+
+//checkRegistrar - beneficiaries are registered by registrars and admins only
+func checkRegistrar(stub shim.ChaincodeStubInterface, callerID string) error {
+	if getCallerRole(stub, callerID) == registrarRole || isCallerAdmin(stub, callerID) {
+		return nil
+	}
+	return errors.New("Only registrars and admins can register beneficiaries or record their consent")
+}
+
+// ============================================================================================================================
+// seedBeneficiarySalt() - store the salt beneficiary ids are derived from, admins only and only once
+//
+// The salt can not be replaced, every beneficiary id would change.
+//
+// Transient - "salt": at least 16 random bytes
+// ============================================================================================================================
+func seedBeneficiarySalt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - seed beneficiary salt")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	if !isCallerAdmin(stub, callerID) {
+		return shim.Error("Only admins can seed the beneficiary salt")
+	}
+
+	stored, err := stub.GetPrivateData(beneficiarySaltCollection, beneficiarySaltKey)
+	if err != nil {
+		return shim.Error("Failed to read the beneficiary salt")
+	}
+	if len(stored) > 0 {
+		return shim.Error("The beneficiary salt is already seeded")
+	}
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error("Failed to read transient data")
+	}
+	salt := transient["salt"]
+	if len(salt) < 16 {
+		return shim.Error("Expecting transient field salt of at least 16 bytes")
+	}
+	err = stub.PutPrivateData(beneficiarySaltCollection, beneficiarySaltKey, salt)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - seed beneficiary salt")
+	return shim.Success(nil)
+}
+
+//parseBeneficiaryIDs reads a JSON array of beneficiary ids and checks every one is registered with consent. Ids listed
+//on the project already are kept whatever their consent, withdrawing it doesn't detach a beneficiary
+func parseBeneficiaryIDs(stub shim.ChaincodeStubInterface, str string, listed []string) ([]string, error) {
+	var ids []string
+	err := json.Unmarshal([]byte(str), &ids)
+	if err != nil {
+		return ids, errors.New("Expecting a JSON array of beneficiary ids")
+	}
+	kept := map[string]bool{}
+	for _, id := range listed {
+		kept[id] = true
+	}
+	for i := range ids {
+		beneficiary, err := getBeneficiary(stub, ids[i])
+		if err != nil {
+			return ids, err
+		}
+		if beneficiary.ConsentStatus != "Granted" && !kept[ids[i]] {
+			return ids, errors.New("Beneficiary " + ids[i] + " has not granted consent")
+		}
+	}
+	return ids, nil
+}
+
+// ============================================================================================================================
+// addBeneficiary() - register a beneficiary, personal details travel in the transient map and stay private
+//
+// Inputs - Array of strings
+//      0     ,    1     ,     2     ,       3
+//  category  , latitude , longitude , consent status (Granted, Pending, Withdrawn)
+//
+// Only registrars and admins can register, the salt has to be seeded with seedBeneficiarySalt first.
+//
+// Transient - "beneficiary": {"identifier":"national id or similar","name":"...","contact":"..."}
+//
+// Returns - the beneficiary id, registering the same person again returns the existing id
+// ============================================================================================================================
+func addBeneficiary(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - add beneficiary")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkRegistrar(stub, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error("Failed to read transient data")
+	}
+	var details BeneficiaryDetails
+	err = json.Unmarshal(transient["beneficiary"], &details)
+	if err != nil || len(details.Identifier) == 0 {
+		return shim.Error("Expecting transient field beneficiary with at least an identifier")
+	}
+
+	salt, err := getBeneficiarySalt(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var beneficiary Beneficiary
+	beneficiary.ObjectType = "Beneficiary"
+	beneficiary.SchemaVersion = currentSchemaVersion
+	beneficiary.BeneficiaryID = beneficiaryID(salt, details.Identifier)
+	beneficiary.Category = args[0]
+	beneficiary.Location, err = parseLocation(args[1], args[2])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	beneficiary.ConsentStatus = args[3]
+	if beneficiary.ConsentStatus != "Granted" && beneficiary.ConsentStatus != "Pending" && beneficiary.ConsentStatus != "Withdrawn" {
+		return shim.Error("Consent status must be Granted, Pending or Withdrawn")
+	}
+
+	//same person, same id
+	_, err = getBeneficiary(stub, beneficiary.BeneficiaryID)
+	if err == nil {
+		log.Println("beneficiary is already registered ", beneficiary.BeneficiaryID)
+		return shim.Success([]byte(beneficiary.BeneficiaryID))
+	}
+	used, err := isKeyUsed(stub, beneficiary.BeneficiaryID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if used {
+		return shim.Error("Id " + beneficiary.BeneficiaryID + " is already used")
+	}
+
+	details.ObjectType = "BeneficiaryDetails"
+	details.SchemaVersion = currentSchemaVersion
+	details.BeneficiaryID = beneficiary.BeneficiaryID
+	detailsAsBytes, _ := json.Marshal(details) //convert to array of bytes
+	err = stub.PutPrivateData(beneficiaryCollection, beneficiary.BeneficiaryID, detailsAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//store beneficiary
+	beneficiary.setCreated(callerID, txTime)
+	beneficiaryAsBytes, _ := json.Marshal(beneficiary) //convert to array of bytes
+	err = stub.PutState(beneficiary.BeneficiaryID, beneficiaryAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - add beneficiary")
+	return shim.Success([]byte(beneficiary.BeneficiaryID))
+}
+
+// ============================================================================================================================
+// updateBeneficiaryConsent() - record a change of consent
+//
+// Projects the beneficiary is attached to keep it, only attaching it to another project needs consent Granted.
+//
+// Inputs - Array of strings
+//        0       ,                 1
+//  beneficiaryId , consent status (Granted, Pending, Withdrawn)
+// ============================================================================================================================
+func updateBeneficiaryConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - update beneficiary consent")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkRegistrar(stub, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	beneficiary, err := getBeneficiary(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if args[1] != "Granted" && args[1] != "Pending" && args[1] != "Withdrawn" {
+		return shim.Error("Consent status must be Granted, Pending or Withdrawn")
+	}
+	beneficiary.ConsentStatus = args[1]
+
+	//store beneficiary
+	beneficiary.setUpdated(callerID, txTime)
+	beneficiaryAsBytes, _ := json.Marshal(beneficiary) //convert to array of bytes
+	err = stub.PutState(beneficiary.BeneficiaryID, beneficiaryAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - update beneficiary consent")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// purgeBeneficiary() - erase the personal details of a beneficiary on request, admins only
+//
+// The public record stays with consent Withdrawn so that projects keep counting the beneficiary.
+//
+// Inputs - Array of strings
+//        0
+//  beneficiaryId
+// ============================================================================================================================
+func purgeBeneficiary(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - purge beneficiary")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if !isCallerAdmin(stub, callerID) {
+		return shim.Error("Only admins can purge beneficiaries")
+	}
+
+	beneficiary, err := getBeneficiary(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.DelPrivateData(beneficiaryCollection, beneficiary.BeneficiaryID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	beneficiary.ConsentStatus = "Withdrawn"
+
+	//store beneficiary
+	beneficiary.setUpdated(callerID, txTime)
+	beneficiaryAsBytes, _ := json.Marshal(beneficiary) //convert to array of bytes
+	err = stub.PutState(beneficiary.BeneficiaryID, beneficiaryAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - purge beneficiary")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// getBeneficiaryCount() - unique beneficiaries across projects, no identities involved
+//
+// Inputs - Array of strings
+//                 0
+//  ["projectId1","projectId2"] or "all"
+// ============================================================================================================================
+func getBeneficiaryCount(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get beneficiary count")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var projects []Project
+	if args[0] == "all" {
+		projectsAsBytes, err := myfunction(stub, "{\"selector\":{\"docType\":\"Project\"}}")
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		json.Unmarshal(projectsAsBytes, &projects)
+	} else {
+		var projectIDs []string
+		err = json.Unmarshal([]byte(args[0]), &projectIDs)
+		if err != nil {
+			return shim.Error("Expecting a JSON array of project ids")
+		}
+		for i := range projectIDs {
+			project, err := getProject(stub, projectIDs[i])
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			projects = append(projects, project)
+		}
+	}
+	projects, err = filterVisibleProjects(stub, projects)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var count BeneficiaryCount
+	count.ByCategory = map[string]int{}
+	count.ByProject = map[string]int{}
+	seen := map[string]bool{}
+	for _, project := range projects {
+		count.ByProject[project.ProjectID] = len(project.BeneficiaryIDs)
+		for _, id := range project.BeneficiaryIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			count.UniqueBeneficiaries++
+			beneficiary, err := getBeneficiary(stub, id)
+			if err == nil {
+				count.ByCategory[beneficiary.Category]++
+			}
+		}
+	}
+
+	countAsBytes, _ := json.Marshal(count) //convert to array of bytes
+
+	log.Println("- end - get beneficiary count")
+	return shim.Success(countAsBytes)
+}
@@ -11,6 +11,7 @@ ordinary GPL, where, by using but not distributing the software, the copyleft pr
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -24,12 +25,23 @@ type SimpleChaincode struct {
 
 //PrivateUser is ...
 type PrivateUser struct {
-	ObjectType string `json:"docType"` //field for couchdb
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
+
+	UserID   string `json:"foundationId"`
+	Username string `json:"Username"`
+	Role     string `json:"role"`
+
+	Attribution
+}
+
+//PrivateUserDetails is ... - private, kept in the user collection only
+type PrivateUserDetails struct {
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
 
 	UserID    string   `json:"foundationId"`
-	Username  string   `json:"Username"`
 	Company   string   `json:"Company"`
-	Role      string   `json:"role"`
 	FirstName string   `json:"firstName"`
 	LastName  string   `json:"lastName"`
 	Location  Location `json:"location"`
@@ -37,65 +49,85 @@ type PrivateUser struct {
 
 //Donor ss
 type Donor struct {
-	ObjectType string `json:"docType"` //field for couchdb
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
 
 	DonorID       string   `json:"donorId"`
 	DonorUsername string   `json:"donorUsername"`
-	DonorCompany  string   `json:"donorCompany"`
 	Donations     []string `json:"donations"`
 	Role          string   `json:"role"`
-	Location      Location `json:"location"`
+
+	Attribution
+}
+
+//DonorDetails ss - private, kept in the user collection only
+type DonorDetails struct {
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
+
+	DonorID      string   `json:"donorId"`
+	DonorCompany string   `json:"donorCompany"`
+	Location     Location `json:"location"`
 }
 
 //Organization ss
 type Organization struct {
-	ObjectType string `json:"docType"` //field for couchdb
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
 
 	OrgID       string   `json:"orgId"`
 	OrgUsername string   `json:"orgUsername"`
 	OrgCompany  string   `json:"orgCompany"`
 	Role        string   `json:"role"`
 	Location    Location `json:"location"`
+
+	Attribution
 }
 
 //Project as
 type Project struct {
-	ObjectType         string          `json:"docType"` //field for couchdb
-	ProjectID          string          `json:"projectId"`
-	ProjectName        string          `json:"projectName"`
-	ProjectType        string          `json:"projectType"`
-	Flag               string          `json:"flag"`
-	FundGoal           float64         `json:"fundGoal"`
-	Currency           string          `json:"currency"`
-	FundRaised         float64         `json:"fundRaised"`
-	FundAllocated      float64         `json:"fundAllocated"`
-	FundNotAllocated   float64         `json:"fundNotAllocated"`
-	ProjectBudget      float64         `json:"projectBudget"`
-	ProjectOwner       string          `json:"projectOwner"`
-	Organization       []projectOrg    `json:"organization"`
-	NGOCompany         []ngoCompany    `json:"ngoCompany"`
-	Beneficiaries      []Beneficiaries `json:"Beneficiaries"`
-	Donations          []string        `json:"donations"`
-	Status             string          `json:"status"`
-	FundAllocationType string          `json:"fundAllocationType"` // 1 = Manual, 2 = Automated, 3 = On Proof Submission, 4 = On Validation
-	TransactionLoc     Location        `json:"transactionLoc"`
-	SDG                []SDG           `json:"SDG"`
-	ProjectLoc         Location        `json:"projectLoc"`
-	CreatedBy          string          `json:"createdBy"`
-	SubRole            string          `json:"subRole"`
-	IsPublished        bool            `json:"isPublished"`
-	IsApproved         bool            `json:"isApproved"`
-	Remarks            string          `json:"remarks"`
-	StartDate          string          `json:"startDate"`
-	EndDate            string          `json:"endDate"`
-	Description        string          `json:"description"`
-	Country            string          `json:"country"`
-	Visibility         string          `json:"visibility"`
+	ObjectType         string       `json:"docType"` //field for couchdb
+	SchemaVersion      int          `json:"schemaVersion"`
+	ProjectID          string       `json:"projectId"`
+	ProjectName        string       `json:"projectName"`
+	ProjectType        string       `json:"projectType"`
+	Flag               string       `json:"flag"`
+	FundGoal           float64      `json:"fundGoal"`
+	Currency           string       `json:"currency"`
+	FundRaised         float64      `json:"fundRaised"`
+	FundAllocated      float64      `json:"fundAllocated"`
+	FundNotAllocated   float64      `json:"fundNotAllocated"`
+	ProjectBudget      float64      `json:"projectBudget"`
+	ProjectOwner       string       `json:"projectOwner"`
+	Organization       []projectOrg `json:"organization"`
+	NGOCompany         []ngoCompany `json:"ngoCompany"`
+	BeneficiaryIDs     []string     `json:"beneficiaryIds"`
+	Donations          []string     `json:"donations"`
+	Status             string       `json:"status"`
+	FundAllocationType string       `json:"fundAllocationType"` // 1 = Manual, 2 = Automated, 3 = On Proof Submission, 4 = On Validation
+	TransactionLoc     Location     `json:"transactionLoc"`
+	SDG                []SDG        `json:"SDG"`
+	ProjectLoc         Location     `json:"projectLoc"`
+	SubRole            string       `json:"subRole"`
+	IsPublished        bool         `json:"isPublished"`
+	IsApproved         bool         `json:"isApproved"`
+	Remarks            string       `json:"remarks"`
+	StartDate          string       `json:"startDate"`
+	EndDate            string       `json:"endDate"`
+	Description        string       `json:"description"`
+	Country            string       `json:"country"`
+	Visibility         string       `json:"visibility"`
+	ProofRadiusKm      float64      `json:"proofRadiusKm"` // proofs must be geotagged within this distance of ProjectLoc, 0 = off
+	ApprovalRound      int          `json:"approvalRound"` // latest review round, 0 when never submitted
+	ClonedFrom         string       `json:"clonedFrom"`    // project or template the structure was copied from
+
+	Attribution
 }
 
 //Milestone as
 type Milestone struct {
 	ObjectType       string   `json:"docType"` //field for couchdb
+	SchemaVersion    int      `json:"schemaVersion"`
 	MilestoneName    string   `json:"milestoneName"`
 	StartDate        string   `json:"startDate"`
 	EndDate          string   `json:"endDate"`
@@ -104,70 +136,411 @@ type Milestone struct {
 	MilBudget        float64  `json:"milestoneBudget"`
 	MilFundAllocated float64  `json:"milFundAllocated"`
 	MilFundRequested float64  `json:"milFundRequested"`
-	MilFundReleased  float64  `json:"MilFundReleased"`
+	MilFundReleased  float64  `json:"milFundReleased"`
 	MilestoneOwner   string   `json:"milestoneOwner"`
 	ActivityCount    int      `json:"activityCount"`
 	Status           string   `json:"status"`
 	TransactionLoc   Location `json:"transactionLoc"`
 	IsApproved       bool     `json:"isApproved"`
 	Description      string   `json:"description"`
+	Overdue          bool     `json:"overdue"`
+	OverdueSince     string   `json:"overdueSince"`
+
+	Attribution
 }
 
 //Activity as
 type Activity struct {
-	ObjectType          string   `json:"docType"` //field for couchdb
-	ActivityName        string   `json:"activityName"`
-	StartDate           string   `json:"startDate"`
-	EndDate             string   `json:"endDate"`
-	ActivityBudget      float64  `json:"activityBudget"`
-	FundAllocated       float64  `json:"fundAllocated"`
-	FundReleased        float64  `json:"fundReleased"`
-	FundRequested       float64  `json:"fundRequested"`
-	ActivityID          string   `json:"activityId"`
-	MilestoneID         string   `json:"milestoneId"`
-	ProjectID           string   `json:"projectId"`
-	Validation          bool     `json:"validation"`
-	Status              string   `json:"status"`
-	ValidatorID         string   `json:"validatorId"`
-	SecondaryValidation bool     `json:"secondaryValidation"`
-	PartialValidation   bool     `json:"partialValidation"`
-	TransactionLoc      Location `json:"transactionLoc"`
-	IsApproved          bool     `json:"isApproved"`
-	Remarks             string   `json:"remarks"`
-	Description         string   `json:"description"`
-	TechnicalCriteria   string   `json:"technicalCriteria"`
-	FinancialCriteria   string   `json:"financialCriteria"`
-	ProofHash           string   `json:"proofHash"`
-}
-
-//Beneficiary List as
-type Beneficiaries struct {
-	Beneficiary string `json:"Beneficiary"`
+	ObjectType          string          `json:"docType"` //field for couchdb
+	SchemaVersion       int             `json:"schemaVersion"`
+	ActivityName        string          `json:"activityName"`
+	StartDate           string          `json:"startDate"`
+	EndDate             string          `json:"endDate"`
+	ActivityBudget      float64         `json:"activityBudget"`
+	FundAllocated       float64         `json:"fundAllocated"`
+	FundReleased        float64         `json:"fundReleased"`
+	FundRequested       float64         `json:"fundRequested"`
+	ActivityID          string          `json:"activityId"`
+	MilestoneID         string          `json:"milestoneId"`
+	ProjectID           string          `json:"projectId"`
+	Validation          bool            `json:"validation"`
+	Status              string          `json:"status"`
+	ValidatorID         string          `json:"validatorId"`
+	SecondaryValidation bool            `json:"secondaryValidation"`
+	PartialValidation   bool            `json:"partialValidation"`
+	TransactionLoc      Location        `json:"transactionLoc"`
+	IsApproved          bool            `json:"isApproved"`
+	Remarks             string          `json:"remarks"`
+	Description         string          `json:"description"`
+	TechnicalCriteria   string          `json:"technicalCriteria"`
+	FinancialCriteria   string          `json:"financialCriteria"`
+	ProofHash           string          `json:"proofHash"`
+	Overdue             bool            `json:"overdue"`
+	OverdueSince        string          `json:"overdueSince"`
+	TechnicalValidation ValidationPanel `json:"technicalValidation"`
+	FinancialValidation ValidationPanel `json:"financialValidation"`
+	ProofVersion        int             `json:"proofVersion"` // latest proof version, 0 when nothing was submitted
+
+	Attribution
+}
+
+//Proof as
+type Proof struct {
+	ObjectType    string          `json:"docType"` //field for couchdb
+	SchemaVersion int             `json:"schemaVersion"`
+	ProofID       string          `json:"proofId"`
+	ActivityID    string          `json:"activityId"`
+	MilestoneID   string          `json:"milestoneId"`
+	ProjectID     string          `json:"projectId"`
+	Version       int             `json:"version"`
+	Documents     []ProofDocument `json:"documents"`
+	SubmittedBy   string          `json:"submittedBy"`
+	SubmittedAt   string          `json:"submittedAt"`
+
+	Attribution
+}
+
+//ProofDocument as
+type ProofDocument struct {
+	Hash          string    `json:"hash"`
+	HashAlgorithm string    `json:"hashAlgorithm"`
+	MimeType      string    `json:"mimeType"`
+	URI           string    `json:"uri"` // off-chain location of the document
+	Geotag        *Location `json:"geotag,omitempty"`
+}
+
+//ProofVerification as
+type ProofVerification struct {
+	Matched       bool   `json:"matched"`
+	ActivityID    string `json:"activityId"`
+	CandidateHash string `json:"candidateHash"`
+	ProofID       string `json:"proofId"`
+	Version       int    `json:"version"`
+	DocumentIndex int    `json:"documentIndex"`
+	HashAlgorithm string `json:"hashAlgorithm"`
+	SubmittedBy   string `json:"submittedBy"`
+	SubmittedAt   string `json:"submittedAt"`
+}
+
+//ValidationVote as
+type ValidationVote struct {
+	Validator    string   `json:"validator"`
+	Verdict      string   `json:"verdict"` // Approved, Rejected
+	Remarks      string   `json:"remarks"`
+	Evidence     []string `json:"evidence"`     // references to the documents the verdict is based on
+	ProofVersion int      `json:"proofVersion"` // proof version the verdict refers to, 0 when none
+	VotedAt      string   `json:"votedAt"`
+}
+
+//ValidationPanel as
+type ValidationPanel struct {
+	ValidatorRole string           `json:"validatorRole"` // registered role every voter must hold, empty for any
+	Validators    []string         `json:"validators"`
+	Threshold     int              `json:"threshold"` // approvals needed out of len(Validators)
+	Votes         []ValidationVote `json:"votes"`
+	Outcome       string           `json:"outcome"` // Pending, Passed, Failed
+}
+
+//OverdueItem as
+type OverdueItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	MilestoneID string `json:"milestoneId"`
+	EndDate     string `json:"endDate"`
+	DaysOverdue int    `json:"daysOverdue"`
+}
+
+//ProjectOverdue as
+type ProjectOverdue struct {
+	ProjectID   string        `json:"projectId"`
+	ProjectName string        `json:"projectName"`
+	Milestones  []OverdueItem `json:"milestones"`
+	Activities  []OverdueItem `json:"activities"`
+}
+
+//Attribution as
+type Attribution struct {
+	CreatedBy string `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedBy string `json:"updatedBy"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+//Beneficiary as
+type Beneficiary struct {
+	ObjectType    string   `json:"docType"` //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"`
+	BeneficiaryID string   `json:"beneficiaryId"` // salted hash of the personal identifier
+	Category      string   `json:"category"`
+	Location      Location `json:"location"`
+	ConsentStatus string   `json:"consentStatus"` // Granted, Pending, Withdrawn
+
+	Attribution
+}
+
+//BeneficiaryDetails as - private, kept in the beneficiary collection only
+type BeneficiaryDetails struct {
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
+	BeneficiaryID string `json:"beneficiaryId"`
+	Identifier    string `json:"identifier"`
+	Name          string `json:"name"`
+	Contact       string `json:"contact"`
+}
+
+//BeneficiaryCount as
+type BeneficiaryCount struct {
+	UniqueBeneficiaries int            `json:"uniqueBeneficiaries"`
+	ByCategory          map[string]int `json:"byCategory"`
+	ByProject           map[string]int `json:"byProject"`
 }
 
 type ngoCompany struct {
+	OrgID   string `json:"orgId"`
 	OrgName string `json:"OrgName"`
 }
 
 type projectOrg struct {
+	OrgID   string `json:"orgId"`
 	OrgName string `json:"OrgName"`
 }
 
+//ApprovalRequest as
+type ApprovalRequest struct {
+	ObjectType        string             `json:"docType"` //field for couchdb
+	SchemaVersion     int                `json:"schemaVersion"`
+	RequestID         string             `json:"requestId"` // <projectId>-APPROVAL-<round>
+	ProjectID         string             `json:"projectId"`
+	Round             int                `json:"round"`
+	RequiredApprovals int                `json:"requiredApprovals"`
+	Decisions         []ApprovalDecision `json:"decisions"`
+	Status            string             `json:"status"` // Pending, Approved, Rejected
+	SubmittedBy       string             `json:"submittedBy"`
+	SubmittedAt       string             `json:"submittedAt"`
+
+	Attribution
+}
+
+//ApprovalDecision as
+type ApprovalDecision struct {
+	Reviewer  string `json:"reviewer"`
+	Verdict   string `json:"verdict"` // Approved, Rejected
+	Remarks   string `json:"remarks"`
+	DecidedAt string `json:"decidedAt"`
+}
+
+//ChangeRequest as
+type ChangeRequest struct {
+	ObjectType        string             `json:"docType"` //field for couchdb
+	SchemaVersion     int                `json:"schemaVersion"`
+	ChangeID          string             `json:"changeId"`   // CR-<txid>
+	EntityType        string             `json:"entityType"` // project, milestone, activity
+	EntityID          string             `json:"entityId"`
+	ProjectID         string             `json:"projectId"`
+	Changes           []FieldChange      `json:"changes"`
+	Justification     string             `json:"justification"`
+	RequiredApprovals int                `json:"requiredApprovals"` // reviewer approvals needed to apply the change
+	Decisions         []ApprovalDecision `json:"decisions"`
+	Status            string             `json:"status"` // Pending, Rejected, Applied
+	ProposedBy        string             `json:"proposedBy"`
+	ProposedAt        string             `json:"proposedAt"`
+	AppliedAt         string             `json:"appliedAt"`
+
+	Attribution
+}
+
+//FieldChange as
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+//Membership as
+type Membership struct {
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
+	MembershipID  string `json:"membershipId"` // orgId_userId
+	OrgID         string `json:"orgId"`
+	UserID        string `json:"userId"`
+	Role          string `json:"role"`   // role within the organization, admins can manage members
+	Status        string `json:"status"` // Invited, Active, Revoked
+	InvitedBy     string `json:"invitedBy"`
+	InvitedAt     string `json:"invitedAt"`
+	AcceptedAt    string `json:"acceptedAt"`
+	RevokedBy     string `json:"revokedBy"`
+	RevokedAt     string `json:"revokedAt"`
+
+	Attribution
+}
+
 //SDG as
 type SDG struct {
 	SDGType string `json:"SDGType"`
 }
 
+//Donation as
+type Donation struct {
+	ObjectType    string  `json:"docType"` //field for couchdb
+	SchemaVersion int     `json:"schemaVersion"`
+	DonationID    string  `json:"donationId"`
+	ProjectID     string  `json:"projectId"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	DonorID       string  `json:"donorId"` // empty for anonymous donations until revealed
+	Anonymous     bool    `json:"anonymous"`
+	Commitment    string  `json:"commitment"` // sha256(projectId|donorId|amount|nonce) of anonymous donations
+	Revealed      bool    `json:"revealed"`
+	DonatedAt     string  `json:"donatedAt"`
+
+	Attribution
+}
+
+//AnonymousDonor as - private, kept in the anonymous donation collection only
+type AnonymousDonor struct {
+	ObjectType    string `json:"docType"` //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"`
+	DonationID    string `json:"donationId"`
+	DonorID       string `json:"donorId"`
+	Nonce         string `json:"nonce"`
+}
+
+//Receipt as - immutable once issued
+type Receipt struct {
+	ObjectType    string   `json:"docType"` //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"`
+	ReceiptID     string   `json:"receiptId"` // RCPT-<fiscal year>-<sequence>
+	DonationID    string   `json:"donationId"`
+	ProjectID     string   `json:"projectId"`
+	ProjectName   string   `json:"projectName"`
+	SDG           []string `json:"SDG"`
+	IssuerOrgID   string   `json:"issuerOrgId"`
+	IssuerOrgName string   `json:"issuerOrgName"`
+	DonorID       string   `json:"donorId"`
+	Amount        float64  `json:"amount"`
+	Currency      string   `json:"currency"`
+	DonationDate  string   `json:"donationDate"`
+	FiscalYear    int      `json:"fiscalYear"`
+	IssuedAt      string   `json:"issuedAt"`
+
+	Attribution
+}
+
+//StatementLine as
+type StatementLine struct {
+	ProjectID   string   `json:"projectId"`
+	ProjectName string   `json:"projectName"`
+	SDG         []string `json:"SDG"`
+	Currency    string   `json:"currency"`
+	Amount      float64  `json:"amount"`
+	ReceiptIDs  []string `json:"receiptIds"`
+}
+
+//DonorStatement as
+type DonorStatement struct {
+	DonorID    string                        `json:"donorId"`
+	FiscalYear int                           `json:"fiscalYear"`
+	Totals     map[string]float64            `json:"totals"` // per currency
+	ByProject  []StatementLine               `json:"byProject"`
+	BySDG      map[string]map[string]float64 `json:"bySDG"` // SDG -> currency -> amount
+	Receipts   []Receipt                     `json:"receipts"`
+}
+
 //ProjectFunds as
 type ProjectFunds struct {
-	DonorName       string  `json:"donorName"`
-	DonoationAmount float64 `json:"donationAmount"`
+	DonorName      string  `json:"donorName"`
+	DonationAmount float64 `json:"donationAmount"`
 }
 
 //Location as
 type Location struct {
-	Latitude  string `json:"latitude"`
-	Longitude string `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+//Config as - the chaincode configuration, stored under the key "config"
+type Config struct {
+	ObjectType         string          `json:"docType"` //field for couchdb
+	SchemaVersion      int             `json:"schemaVersion"`
+	Version            int             `json:"version"`
+	UIVersion          string          `json:"uiVersion"` // compatible projects application version
+	AdminMSPs          []string        `json:"adminMSPs"` // MSPs whose admins may change the configuration, empty for any
+	DefaultCurrency    string          `json:"defaultCurrency"`
+	Currencies         []string        `json:"currencies"`         // allowed project currencies, empty for any
+	ValidatorThreshold int             `json:"validatorThreshold"` // approvals of a validator panel set with threshold "default"
+	ReviewApprovals    int             `json:"reviewApprovals"`    // reviewer approvals needed to publish a project
+	MaxPageSize        int             `json:"maxPageSize"`
+	AllocationTypes    map[string]bool `json:"allocationTypes"` // switches for the fund allocation types 1 - 4
+	MaxArgLength       int             `json:"maxArgLength"`
+	MaxArgCount        int             `json:"maxArgCount"`
+	MaxBatchOperations int             `json:"maxBatchOperations"` // operations accepted by one batch
+	IDScheme           string          `json:"idScheme"`           // ids generated for "auto", counter (PRJ-2026-000123) or txid
+
+	Attribution
+}
+
+//ProjectTemplate as - the structure of a project without funds, proofs or approvals
+type ProjectTemplate struct {
+	ObjectType      string      `json:"docType"` //field for couchdb
+	SchemaVersion   int         `json:"schemaVersion"`
+	TemplateID      string      `json:"templateId"`
+	TemplateName    string      `json:"templateName"`
+	SourceProjectID string      `json:"sourceProjectId"`
+	Project         Project     `json:"project"`
+	Milestones      []Milestone `json:"milestones"`
+	Activities      []Activity  `json:"activities"`
+
+	Attribution
+}
+
+//BatchOperation as - one invoke of a batch
+type BatchOperation struct {
+	Function string         `json:"function"`
+	Args     []string       `json:"args"`
+	Refs     map[string]int `json:"refs"` // argument position -> index of the operation whose id it takes
+}
+
+//BatchResult as
+type BatchResult struct {
+	Index    int             `json:"index"`
+	Function string          `json:"function"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+//ProjectTotals as - budget figures summed over the activities, unallocated is the budget still without funds.
+//Raised and notAllocated are the donations of the project and the part of them not allocated yet, project level only
+type ProjectTotals struct {
+	Budget       float64 `json:"budget"`
+	Allocated    float64 `json:"allocated"`
+	Requested    float64 `json:"requested"`
+	Released     float64 `json:"released"`
+	Unallocated  float64 `json:"unallocated"`
+	Raised       float64 `json:"raised,omitempty"`
+	NotAllocated float64 `json:"notAllocated,omitempty"`
+}
+
+//ProjectTree as
+type ProjectTree struct {
+	Project         Project         `json:"project"`
+	Totals          ProjectTotals   `json:"totals"`
+	PercentComplete float64         `json:"percentComplete"`
+	Milestones      []MilestoneTree `json:"milestones"`
+}
+
+//MilestoneTree as
+type MilestoneTree struct {
+	Milestone       Milestone      `json:"milestone"`
+	Totals          ProjectTotals  `json:"totals"`
+	PercentComplete float64        `json:"percentComplete"`
+	Activities      []ActivityNode `json:"activities"`
+}
+
+//ActivityNode as
+type ActivityNode struct {
+	Activity        Activity      `json:"activity"`
+	Totals          ProjectTotals `json:"totals"`
+	PercentComplete float64       `json:"percentComplete"`
 }
 
 // ============================================================================================================================
@@ -182,6 +555,15 @@ func main() {
 
 // ============================================================================================================================
 // Init - initialize the chaincode - projects don’t need anything initlization, so let's run a dead simple test instead
+//
+// Inputs - Array of strings
+//    0     ,          1           ,         2
+//  number  , bootstrap admin id   , configuration JSON
+//
+// The bootstrap admin enables write() for that identity until closeBootstrap, pass "" to skip it. It is only seeded
+// once, upgrades have to pass "" or nothing. The configuration is required on instantiation, it has to name the
+// adminMSPs. Upgrades keep the stored configuration, it is only changed with updateConfig. Init is not routed
+// through Invoke.
 // ============================================================================================================================
 func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	fmt.Println("Projects Is Starting Up")
@@ -189,8 +571,8 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	var Aval int
 	var err error
 
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+	if len(args) < 1 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 1 to 3")
 	}
 
 	// convert numeric string to integer
@@ -199,8 +581,37 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 		return shim.Error("Expecting a numeric string argument to Init()")
 	}
 
+	// store the configuration, a new one is versioned on top of the stored one
+	config, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(args) == 3 {
+		if config.Version > 0 {
+			return shim.Error("The configuration is already stored, use updateConfig to change it")
+		}
+		previousVersion := config.Version
+		attribution := config.Attribution
+		config = defaultConfig()
+		err = json.Unmarshal([]byte(args[2]), &config)
+		if err != nil {
+			return shim.Error("Expecting the configuration as JSON object")
+		}
+		config.Attribution = attribution
+		txTime, err := getTxTime(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = putConfig(stub, config, previousVersion, "init", txTime)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	} else if config.Version == 0 {
+		return shim.Error("Init needs a configuration naming the adminMSPs")
+	}
+
 	// store compaitible projects application version
-	err = stub.PutState("projects_ui", []byte("3.5.0"))
+	err = stub.PutState("projects_ui", []byte(config.UIVersion))
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -211,6 +622,35 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 		return shim.Error(err.Error()) //self-test fail
 	}
 
+	// the generic write is only open while bootstrapping, an upgrade without bootstrap admin closes it again
+	if len(args) >= 2 && len(args[1]) > 0 {
+		seeded, err := stub.GetState(adminSeededKey)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if len(seeded) > 0 {
+			return shim.Error("The bootstrap admin is already seeded, upgrade without one")
+		}
+		txTime, err := getTxTime(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = seedAdmin(stub, args[1], txTime)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutState(adminSeededKey, []byte(args[1]))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutState(bootstrapAdminKey, []byte(args[1]))
+	} else {
+		err = stub.DelState(bootstrapAdminKey)
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	fmt.Println(" - ready for action") //self-test pass
 	return shim.Success(nil)
 }
@@ -228,22 +668,42 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	}
 
 	// Handle different functions
-	if function == "init" {
-		return t.Init(stub)
-	} else if function == "read" {
+	if function == "read" {
 		return read(stub, args)
 	} else if function == "write" {
 		return write(stub, args)
+	} else if function == "closeBootstrap" {
+		return closeBootstrap(stub, args)
+	} else if function == "updateConfig" {
+		return updateConfig(stub, args)
+	} else if function == "readConfig" {
+		return readConfig(stub, args)
+	} else if function == "migrateData" {
+		return migrateData(stub, args)
+	} else if function == "batch" {
+		return batch(stub, args)
+	} else if function == "cloneProject" {
+		return cloneProject(stub, args)
+	} else if function == "addProjectTemplate" {
+		return addProjectTemplate(stub, args)
+	} else if function == "createProjectFromTemplate" {
+		return createProjectFromTemplate(stub, args)
 	} else if function == "invke" {
 		return invke(stub, args)
 	} else if function == "addPrivateUser" { // Registration API's
 		return addPrivateUser(stub, args)
 	} else if function == "updatePrivateUser" {
 		return updatePrivateUser(stub, args)
+	} else if function == "adminRegisterUser" {
+		return adminRegisterUser(stub, args)
 	} else if function == "addDonor" {
 		return addDonor(stub, args)
 	} else if function == "updateDonor" {
 		return updateDonor(stub, args)
+	} else if function == "getUserDetails" {
+		return getUserDetails(stub, args)
+	} else if function == "purgePrivateUser" {
+		return purgePrivateUser(stub, args)
 	} else if function == "addAdmin" {
 		return addOrg(stub, args)
 	} else if function == "updateAdmin" {
@@ -282,18 +742,80 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return query(stub, args)
 	} else if function == "query_all" {
 		return query_all(stub, args)
+	} else if function == "getProjectTree" {
+		return getProjectTree(stub, args)
+	} else if function == "getOverdue" {
+		return getOverdue(stub, args)
+	} else if function == "markOverdue" {
+		return markOverdue(stub, args)
 	} else if function == "fundProject" {
 		return fundProject(stub, args)
+	} else if function == "fundProjectAnonymous" {
+		return fundProjectAnonymous(stub, args)
+	} else if function == "revealDonation" {
+		return revealDonation(stub, args)
+	} else if function == "verifyDonation" {
+		return verifyDonation(stub, args)
+	} else if function == "issueReceipt" {
+		return issueReceipt(stub, args)
+	} else if function == "getDonorStatement" {
+		return getDonorStatement(stub, args)
+	} else if function == "submitProjectForReview" {
+		return submitProjectForReview(stub, args)
+	} else if function == "reviewProject" {
+		return reviewProject(stub, args)
+	} else if function == "publishProject" {
+		return publishProject(stub, args)
+	} else if function == "getApprovalTrail" {
+		return getApprovalTrail(stub, args)
+	} else if function == "proposeChange" {
+		return proposeChange(stub, args)
+	} else if function == "decideChange" {
+		return decideChange(stub, args)
+	} else if function == "getChangeRequests" {
+		return getChangeRequests(stub, args)
+	} else if function == "inviteMember" {
+		return inviteMember(stub, args)
+	} else if function == "acceptMembership" {
+		return acceptMembership(stub, args)
+	} else if function == "revokeMembership" {
+		return revokeMembership(stub, args)
+	} else if function == "getMemberships" {
+		return getMemberships(stub, args)
 	} else if function == "submitProof" {
 		return submitProof(stub, args)
+	} else if function == "getProofs" {
+		return getProofs(stub, args)
+	} else if function == "verifyProof" {
+		return verifyProof(stub, args)
 	} else if function == "fundAllocateManually" {
 		return fundAllocateManually(stub, args)
 	} else if function == "balancedfundAllocate" {
 		return balancedfundAllocate(stub, args)
 	} else if function == "updateProjectVisibility" { // Flow API's
 		return updateProjectVisibility(stub, args)
+	} else if function == "updateProjectGeofence" {
+		return updateProjectGeofence(stub, args)
+	} else if function == "getProjectsInBox" {
+		return getProjectsInBox(stub, args)
+	} else if function == "getProjectsNearby" {
+		return getProjectsNearby(stub, args)
+	} else if function == "addBeneficiary" { // Beneficiary API's
+		return addBeneficiary(stub, args)
+	} else if function == "updateBeneficiaryConsent" {
+		return updateBeneficiaryConsent(stub, args)
+	} else if function == "purgeBeneficiary" {
+		return purgeBeneficiary(stub, args)
+	} else if function == "seedBeneficiarySalt" {
+		return seedBeneficiarySalt(stub, args)
+	} else if function == "getBeneficiaryCount" {
+		return getBeneficiaryCount(stub, args)
 	} else if function == "updateActivityValidation" { // Flow API's
 		return updateActivityValidation(stub, args)
+	} else if function == "voteActivityValidation" {
+		return voteActivityValidation(stub, args)
+	} else if function == "setValidatorPanel" {
+		return setValidatorPanel(stub, args)
 	}
 
 	// error out
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//testStub is a MockStub that knows the caller, the transient map and plain CouchDB selectors, which the MockStub of
+//Fabric 1.4 leaves unimplemented
+type testStub struct {
+	*shim.MockStub
+	args      [][]byte
+	creator   []byte
+	transient map[string][]byte
+	txCount   int
+}
+
+func (s *testStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *testStub) GetStringArgs() []string {
+	var args []string
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *testStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *testStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+//GetQueryResult answers selectors of plain field values from the mock state, the same ones a batch supports
+func (s *testStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	selector, err := parseBatchSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key, value := range s.State {
+		if matchesSelector(selector, value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var results []*queryresult.KV
+	for _, key := range keys {
+		results = append(results, &queryresult.KV{Key: key, Value: s.State[key]})
+	}
+	return &batchIterator{results: results}, nil
+}
+
+func (s *testStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	resultsIterator, err := s.GetQueryResult(query)
+	return resultsIterator, &pb.QueryResponseMetadata{}, err
+}
+
+//run executes one transaction of the caller against the chaincode
+func (s *testStub) run(creator []byte, transient map[string][]byte, init bool, function string, args ...string) pb.Response {
+	s.creator = creator
+	s.transient = transient
+	s.args = [][]byte{[]byte(function)}
+	for _, arg := range args {
+		s.args = append(s.args, []byte(arg))
+	}
+	s.txCount++
+	txID := "tx" + strconv.Itoa(s.txCount)
+	s.MockTransactionStart(txID)
+	defer s.MockTransactionEnd(txID)
+	if init {
+		return new(SimpleChaincode).Init(s)
+	}
+	return new(SimpleChaincode).Invoke(s)
+}
+
+func (s *testStub) invoke(creator []byte, function string, args ...string) pb.Response {
+	return s.run(creator, nil, false, function, args...)
+}
+
+//callerID is the id the chaincode sees for the identity
+func (s *testStub) callerID(t *testing.T, creator []byte) string {
+	s.creator = creator
+	id, err := getCallerID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+//newTestIdentity serializes a self-signed certificate the way the peer passes the creator to the chaincode
+func newTestIdentity(t *testing.T, mspID string, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certificatePEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+//testNetwork is a chaincode instantiated by admin, with a project owner, a validator registered by admin and an
+//outsider of another organization
+type testNetwork struct {
+	stub      *testStub
+	admin     []byte
+	owner     []byte
+	validator []byte
+	outsider  []byte
+}
+
+const testConfig = `{"adminMSPs":["Org1MSP"]}`
+
+func newTestNetwork(t *testing.T) *testNetwork {
+	n := &testNetwork{
+		stub:      &testStub{MockStub: shim.NewMockStub("projects", new(SimpleChaincode))},
+		admin:     newTestIdentity(t, "Org1MSP", "admin"),
+		owner:     newTestIdentity(t, "Org1MSP", "owner"),
+		validator: newTestIdentity(t, "Org2MSP", "validator"),
+		outsider:  newTestIdentity(t, "Org2MSP", "outsider"),
+	}
+	expectOK(t, n.stub.run(n.admin, nil, true, "init", "1", n.stub.callerID(t, n.admin), testConfig))
+	details := map[string][]byte{"user": []byte(`{"firstName":"Vera"}`)}
+	expectOK(t, n.stub.run(n.admin, details, false, "adminRegisterUser", "user", n.stub.callerID(t, n.validator), "vera", defaultValidatorRole))
+	return n
+}
+
+func expectOK(t *testing.T, response pb.Response) {
+	t.Helper()
+	if response.Status != shim.OK {
+		t.Fatalf("expected success, got %d: %s", response.Status, response.Message)
+	}
+}
+
+func expectError(t *testing.T, response pb.Response) {
+	t.Helper()
+	if response.Status == shim.OK {
+		t.Fatalf("expected an error, got success")
+	}
+}
+
+func TestInitIsNotInvokable(t *testing.T) {
+	n := newTestNetwork(t)
+
+	expectError(t, n.stub.invoke(n.outsider, "init", "1", n.stub.callerID(t, n.outsider), `{"adminMSPs":["Org2MSP"]}`))
+
+	config, err := getConfig(n.stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.AdminMSPs) != 1 || config.AdminMSPs[0] != "Org1MSP" {
+		t.Fatalf("configuration was replaced: %v", config.AdminMSPs)
+	}
+	if isAdmin(n.stub, n.stub.callerID(t, n.outsider)) {
+		t.Fatal("outsider became admin")
+	}
+}
+
+func TestUpgradeKeepsConfigurationAndBootstrapAdmin(t *testing.T) {
+	n := newTestNetwork(t)
+
+	expectError(t, n.stub.run(n.admin, nil, true, "init", "1", "", `{"adminMSPs":["Org2MSP"]}`))
+	expectError(t, n.stub.run(n.admin, nil, true, "init", "1", n.stub.callerID(t, n.outsider)))
+	if isAdmin(n.stub, n.stub.callerID(t, n.outsider)) {
+		t.Fatal("upgrade seeded another admin")
+	}
+
+	//an upgrade without bootstrap admin closes the generic write for good
+	expectOK(t, n.stub.run(n.admin, nil, true, "init", "1"))
+	expectError(t, n.stub.invoke(n.admin, "write", "key", "key", "value"))
+	expectError(t, n.stub.run(n.admin, nil, true, "init", "1", n.stub.callerID(t, n.admin)))
+	expectError(t, n.stub.invoke(n.admin, "write", "key", "key", "value"))
+}
+
+func TestUpdateConfigNeedsAdmin(t *testing.T) {
+	n := newTestNetwork(t)
+
+	expectError(t, n.stub.invoke(n.outsider, "updateConfig", `{"adminMSPs":["Org2MSP"]}`, "1"))
+	expectError(t, n.stub.invoke(n.owner, "updateConfig", `{"adminMSPs":["Org1MSP"]}`, "1"))
+	expectOK(t, n.stub.invoke(n.admin, "updateConfig", `{"adminMSPs":["Org1MSP"],"maxPageSize":50}`, "1"))
+}
+
+func TestWriteOnlyWhileBootstrapping(t *testing.T) {
+	n := newTestNetwork(t)
+
+	expectError(t, n.stub.invoke(n.outsider, "write", "key", "key", "value"))
+	expectOK(t, n.stub.invoke(n.admin, "write", "key", "key", "value"))
+	expectError(t, n.stub.invoke(n.outsider, "closeBootstrap", "close"))
+	expectOK(t, n.stub.invoke(n.admin, "closeBootstrap", "close"))
+	expectError(t, n.stub.invoke(n.admin, "write", "key", "key", "other"))
+}
+
+func TestReadOnlyReturnsTypedDocuments(t *testing.T) {
+	n := newTestNetwork(t)
+
+	expectError(t, n.stub.invoke(n.outsider, "read", "Config", string(configKey)))
+	expectError(t, n.stub.invoke(n.outsider, "read", "Project", "selftest"))
+	expectError(t, n.stub.invoke(n.outsider, "read", "PrivateUser", n.stub.callerID(t, n.owner)))
+	expectOK(t, n.stub.invoke(n.outsider, "read", "PrivateUser", n.stub.callerID(t, n.admin)))
+}
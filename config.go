@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//configKey holds the chaincode configuration document, readConfig lists its versions
+var configKey = reservedKey("config")
+
+//=============== CONFIGURATION RELATED FUNCTION'S START HERE =================================================================
+
+//defaultConfig is used until a configuration is stored, it matches the behaviour before the configuration existed
+func defaultConfig() Config {
+	return Config{
+		ObjectType:         "Config",
+		SchemaVersion:      currentSchemaVersion,
+		UIVersion:          "3.5.0",
+		AdminMSPs:          []string{},
+		DefaultCurrency:    "USD",
+		Currencies:         []string{},
+		ValidatorThreshold: 1,
+		ReviewApprovals:    2,
+		MaxPageSize:        100,
+		AllocationTypes:    map[string]bool{"1": true, "2": true, "3": true, "4": true},
+		MaxArgLength:       2000,
+		MaxArgCount:        64,
+		MaxBatchOperations: 100,
+		IDScheme:           "counter",
+	}
+}
+
+// ============================================================================================================================
+// Get Config - get the configuration from ledger, the defaults when none is stored
+// ============================================================================================================================
+func getConfig(stub shim.ChaincodeStubInterface) (Config, error) {
+	config := defaultConfig()
+	configAsBytes, err := stub.GetState(configKey) //getState retreives a key/value from the ledger
+	if err != nil {
+		return config, errors.New("Failed to get configuration")
+	}
+	if len(configAsBytes) == 0 {
+		return config, nil
+	}
+	err = unmarshalDocument(configAsBytes, &config) //un stringify it aka JSON.parse(), migrated to the current schema
+	if err != nil {
+		return config, errors.New("Configuration is corrupt")
+	}
+	return config, nil
+}
+
+//validateConfig rejects configurations the chaincode cannot work with
+func validateConfig(config Config) error {
+	if len(config.AdminMSPs) == 0 {
+		return errors.New("adminMSPs must name at least one MSP")
+	}
+	if config.ValidatorThreshold < 1 {
+		return errors.New("validatorThreshold must be at least 1")
+	}
+	if config.ReviewApprovals < 1 {
+		return errors.New("reviewApprovals must be at least 1")
+	}
+	if config.MaxPageSize < 1 {
+		return errors.New("maxPageSize must be at least 1")
+	}
+	if config.MaxArgLength < 1 || config.MaxArgCount < 1 {
+		return errors.New("maxArgLength and maxArgCount must be at least 1")
+	}
+	if config.MaxBatchOperations < 1 {
+		return errors.New("maxBatchOperations must be at least 1")
+	}
+	if config.IDScheme != "counter" && config.IDScheme != "txid" {
+		return errors.New("idScheme must be counter or txid")
+	}
+	if len(config.DefaultCurrency) == 0 {
+		return errors.New("defaultCurrency must be set")
+	}
+	if !isCurrencyAllowed(config, config.DefaultCurrency) {
+		return errors.New("defaultCurrency " + config.DefaultCurrency + " is not an allowed currency")
+	}
+	return nil
+}
+
+//putConfig stores the next version of the configuration
+func putConfig(stub shim.ChaincodeStubInterface, config Config, previousVersion int, actor string, txTime string) error {
+	err := validateConfig(config)
+	if err != nil {
+		return err
+	}
+	config.ObjectType = "Config"
+	config.SchemaVersion = currentSchemaVersion
+	config.Version = previousVersion + 1
+	config.setUpdated(actor, txTime)
+	configAsBytes, _ := json.Marshal(config) //convert to array of bytes
+	return stub.PutState(configKey, configAsBytes)
+}
+
+//isCurrencyAllowed - an empty currency list allows every currency
+func isCurrencyAllowed(config Config, currency string) bool {
+	if len(config.Currencies) == 0 {
+		return true
+	}
+	for _, allowed := range config.Currencies {
+		if allowed == currency {
+			return true
+		}
+	}
+	return false
+}
+
+//checkCurrency - error unless the currency is allowed by the configuration
+func checkCurrency(stub shim.ChaincodeStubInterface, currency string) error {
+	config, err := getConfig(stub)
+	if err != nil {
+		return err
+	}
+	if !isCurrencyAllowed(config, currency) {
+		return errors.New("Currency " + currency + " is not allowed")
+	}
+	return nil
+}
+
+//checkAllocationType - error unless the fund allocation type is switched on in the configuration
+func checkAllocationType(stub shim.ChaincodeStubInterface, allocationType string) error {
+	config, err := getConfig(stub)
+	if err != nil {
+		return err
+	}
+	if !config.AllocationTypes[allocationType] {
+		return errors.New("Fund allocation type " + allocationType + " is not enabled")
+	}
+	return nil
+}
+
+//isAdminMSP - the caller belongs to one of the admin MSPs, none without a stored configuration
+func isAdminMSP(stub shim.ChaincodeStubInterface) bool {
+	config, err := getConfig(stub)
+	if err != nil {
+		return false
+	}
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return false
+	}
+	for _, adminMSP := range config.AdminMSPs {
+		if adminMSP == mspID {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================================================================
+// updateConfig() - an admin of one of the admin MSPs replaces the configuration
+//
+// The version has to match the stored one, so concurrent edits do not silently overwrite each other. Older versions
+// stay available to admins through readConfig with "history".
+//
+// Inputs - Array of strings
+//        0        ,      1
+//  config JSON    , current version
+// ============================================================================================================================
+func updateConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - update config")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	if !isCallerAdmin(stub, callerID) || !isAdminMSP(stub) {
+		return shim.Error("Only admins of the admin MSPs can update the configuration")
+	}
+
+	current, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if strconv.Itoa(current.Version) != args[1] {
+		return shim.Error("Configuration is at version " + strconv.Itoa(current.Version) + ", not " + args[1])
+	}
+
+	config := defaultConfig()
+	err = json.Unmarshal([]byte(args[0]), &config)
+	if err != nil {
+		return shim.Error("Expecting the configuration as JSON object")
+	}
+	config.Attribution = current.Attribution
+	err = putConfig(stub, config, current.Version, callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - update config")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// readConfig() - the current configuration, or every version of it for admins
+//
+// Inputs - Array of strings
+//         0
+//  "current" | "history"
+// ============================================================================================================================
+func readConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	log.Println("starting - read config")
+
+	if args[0] == "history" {
+		callerID, err := getCallerID(stub)
+		if err != nil {
+			return shim.Error("Error retrieving caller identity")
+		}
+		if !isCallerAdmin(stub, callerID) {
+			return shim.Error("Only admins can read the history of the configuration")
+		}
+		return keyHistory(stub, configKey, "Config")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	configAsBytes, _ := json.Marshal(config) //convert to array of bytes
+
+	log.Println("- end - read config")
+	return shim.Success(configAsBytes)
+}
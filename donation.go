@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//anonymousDonationCollection prefixes the collections that hold who is behind an anonymous donation, every
+//organization has its own one named after its MSP ID, e.g. collectionAnonymousDonationsOrg1MSP, see collections_config.json
+const anonymousDonationCollection = "collectionAnonymousDonations"
+
+//anonymousActor replaces the caller in createdBy and updatedBy of every record an anonymous donation writes
+const anonymousActor = "anonymous"
+
+//=============== DONATION RECORD RELATED FUNCTION'S START HERE ===============================================================
+
+// ============================================================================================================================
+// Get Donation - get the public donation record from ledger
+// ============================================================================================================================
+func getDonation(stub shim.ChaincodeStubInterface, id string) (Donation, error) {
+	var donation Donation
+	donationAsBytes, err := stub.GetState(id) //getState retreives a key/value from the ledger
+	if err != nil {                           //this seems to always succeed, even if key didn't exist
+		return donation, errors.New("Failed to get donation - " + id)
+	}
+	unmarshalDocument(donationAsBytes, &donation) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(donation.DonationID) == 0 { //test if donation is actually here or just nil
+		return donation, errors.New("donation does not exist - " + id)
+	}
+
+	return donation, nil
+}
+
+//donationCommitment binds donor, project and amount to a secret nonce known only to the donor
+func donationCommitment(projectID string, donorID string, amount float64, nonce string) string {
+	digest := sha256.Sum256([]byte(projectID + "|" + donorID + "|" + strconv.FormatFloat(amount, 'f', -1, 64) + "|" + nonce))
+	return hex.EncodeToString(digest[:])
+}
+
+//newDonation stores the public record of a donation, the donor is left empty for anonymous donations
+func newDonation(stub shim.ChaincodeStubInterface, project Project, amount float64, donorID string, txTime string) (Donation, error) {
+	var donation Donation
+	donation.ObjectType = "Donation"
+	donation.SchemaVersion = currentSchemaVersion
+	donation.DonationID = "DON-" + stub.GetTxID()
+	donation.ProjectID = project.ProjectID
+	donation.Amount = amount
+	donation.Currency = project.Currency
+	if len(donation.Currency) == 0 {
+		config, err := getConfig(stub)
+		if err != nil {
+			return donation, err
+		}
+		donation.Currency = config.DefaultCurrency
+	}
+	donation.DonorID = donorID
+	donation.DonatedAt = txTime
+	donation.setCreated(donorID, txTime)
+
+	donationAsBytes, _ := json.Marshal(donation) //convert to array of bytes
+	err := stub.PutState(donation.DonationID, donationAsBytes)
+	return donation, err
+}
+
+//addDonationToDonor lists the donation on the donor's record, callers without a donor record are skipped
+func addDonationToDonor(stub shim.ChaincodeStubInterface, donorID string, donationID string, txTime string) error {
+	donorUser, err := getDonor(stub, donorID)
+	if err != nil {
+		return nil
+	}
+	donorUser.Donations = append(donorUser.Donations, donationID)
+	donorUser.setUpdated(donorID, txTime)
+	userAsBytes, _ := json.Marshal(donorUser)
+	return stub.PutState(donorUser.DonorID, userAsBytes)
+}
+
+// ============================================================================================================================
+// fundProjectAnonymous() - donate without appearing on the public ledger
+//
+// Only the amount and a commitment hash are public, every public record it writes is attributed to "anonymous". Who
+// donated is kept in the anonymous donation collection of the donor's organization. The donor has to be the caller.
+// The commitment is sha256(projectId|donorId|amount|nonce) with the amount in shortest decimal form.
+//
+// Inputs - Array of strings
+//      0     ,    1   ,  2
+//  projectId , amount , flag
+//
+// Transient - "donation": {"donorId":"...","nonce":"secret of at least 16 characters"}
+//
+// Returns - the donation id
+// ============================================================================================================================
+func fundProjectAnonymous(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - fund project anonymously")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var secret AnonymousDonor
+	err = getTransientJSON(stub, "donation", &secret)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(secret.DonorID) == 0 || len(secret.Nonce) < 16 {
+		return shim.Error("Transient field donation needs a donorId and a nonce of at least 16 characters")
+	}
+	if secret.DonorID != callerID {
+		return shim.Error("Anonymous donations can only be made by the donor")
+	}
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return shim.Error("failed to get MSP ID of the caller")
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing ", args[0])
+		return shim.Error(err.Error())
+	}
+
+	var amount = parseFloat(args[1])
+	if amount <= 0 {
+		return shim.Error("Donation amount must be a positive number")
+	}
+	commitment := donationCommitment(project.ProjectID, secret.DonorID, amount, secret.Nonce)
+
+	project.Flag = args[2]
+	err = allocateDonation(stub, &project, amount, anonymousActor+":"+commitment[:12], anonymousActor, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//record the donation, public part without the donor
+	donation, err := newDonation(stub, project, amount, "", txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	donation.Anonymous = true
+	donation.Commitment = commitment
+	donation.setCreated(anonymousActor, txTime)
+	donationAsBytes, _ := json.Marshal(donation) //convert to array of bytes
+	err = stub.PutState(donation.DonationID, donationAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//private part
+	secret.ObjectType = "AnonymousDonor"
+	secret.SchemaVersion = currentSchemaVersion
+	secret.DonationID = donation.DonationID
+	secretAsBytes, _ := json.Marshal(secret) //convert to array of bytes
+	err = stub.PutPrivateData(anonymousDonationCollection+mspID, donation.DonationID, secretAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//store project
+	project.setUpdated(anonymousActor, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	err = stub.PutState(project.ProjectID, projectAsBytes)
+	if err != nil {
+		log.Println("could not fund project")
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - fund project anonymously")
+	return shim.Success([]byte(donation.DonationID))
+}
+
+// ============================================================================================================================
+// revealDonation() - the donor makes an anonymous donation public
+//
+// Inputs - Array of strings
+//      0
+//  donationId
+//
+// Transient - "donation": {"donorId":"...","nonce":"..."} as used for the donation
+// ============================================================================================================================
+func revealDonation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - reveal donation")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var secret AnonymousDonor
+	err = getTransientJSON(stub, "donation", &secret)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	donation, err := getDonation(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !donation.Anonymous || donation.Revealed {
+		return shim.Error("Donation " + donation.DonationID + " is not an undisclosed anonymous donation")
+	}
+	if donationCommitment(donation.ProjectID, secret.DonorID, donation.Amount, secret.Nonce) != donation.Commitment {
+		return shim.Error("Donor and nonce do not match the commitment of donation " + donation.DonationID)
+	}
+
+	donation.DonorID = secret.DonorID
+	donation.Revealed = true
+
+	//store donation
+	donation.setUpdated(callerID, txTime)
+	donationAsBytes, _ := json.Marshal(donation) //convert to array of bytes
+	err = stub.PutState(donation.DonationID, donationAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addDonationToDonor(stub, donation.DonorID, donation.DonationID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - reveal donation")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// verifyDonation() - prove to a third party, e.g. a tax authority, that an anonymous donation belongs to a donor
+// without publishing anything. Run it as a query.
+//
+// Inputs - Array of strings
+//      0      ,    1    ,   2
+//  donationId , donorId , nonce
+// ============================================================================================================================
+func verifyDonation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - verify donation")
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	donation, err := getDonation(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCanViewProject(stub, donation.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	matched := donation.DonorID == args[1]
+	if donation.Anonymous {
+		matched = donationCommitment(donation.ProjectID, args[1], donation.Amount, args[2]) == donation.Commitment
+	}
+
+	log.Println("- end - verify donation")
+	return shim.Success([]byte("{\"donationId\":\"" + donation.DonationID + "\",\"matched\":" + strconv.FormatBool(matched) + "}"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func anonymousDonation(donorID string) map[string][]byte {
+	secret, _ := json.Marshal(AnonymousDonor{DonorID: donorID, Nonce: "0123456789abcdef"})
+	return map[string][]byte{"donation": secret}
+}
+
+func TestAnonymousDonationKeepsTheDonorOffTheLedger(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+	donor := n.stub.callerID(t, n.outsider)
+
+	response := n.stub.run(n.outsider, anonymousDonation(donor), false, "fundProjectAnonymous", "PRJ-1", "400", "donated")
+	expectOK(t, response)
+	donationID := string(response.Payload)
+
+	for key, value := range n.stub.State {
+		if strings.Contains(key, donor) || strings.Contains(string(value), donor) {
+			t.Fatalf("donor is public in %q: %s", key, value)
+		}
+	}
+	donation, err := getDonation(n.stub, donationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _ := getProject(n.stub, "PRJ-1")
+	activity, _ := getActivity(n.stub, "PRJ-1-A1")
+	if donation.CreatedBy != anonymousActor || project.UpdatedBy != anonymousActor || activity.UpdatedBy != anonymousActor {
+		t.Fatalf("donation by %s, project and activity updated by %s and %s", donation.CreatedBy, project.UpdatedBy, activity.UpdatedBy)
+	}
+	if project.FundRaised != 400 || activity.FundAllocated != 400 {
+		t.Fatalf("project raised %v, activity allocated %v", project.FundRaised, activity.FundAllocated)
+	}
+
+	var secret AnonymousDonor
+	json.Unmarshal(n.stub.PvtState[anonymousDonationCollection+"Org2MSP"][donationID], &secret)
+	if secret.DonorID != donor {
+		t.Fatal("donor is missing in the collection of its organization")
+	}
+	if len(n.stub.PvtState[anonymousDonationCollection+"Org1MSP"]) > 0 {
+		t.Fatal("donor is shared with another organization")
+	}
+}
+
+func TestAnonymousDonationOnlyByTheDonor(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+
+	expectError(t, n.stub.run(n.outsider, anonymousDonation(n.stub.callerID(t, n.owner)), false, "fundProjectAnonymous", "PRJ-1", "100", "donated"))
+	project, _ := getProject(n.stub, "PRJ-1")
+	if project.FundRaised != 0 {
+		t.Fatalf("project raised %v", project.FundRaised)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//earthRadiusKm is the mean earth radius used for distances
+const earthRadiusKm = 6371.0
+
+//=============== GEOLOCATION RELATED FUNCTION'S START HERE ===============================================================
+
+//parseLocation will parse and range check a latitude and longitude pair
+func parseLocation(latitude string, longitude string) (Location, error) {
+	var location Location
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latitude), 64)
+	if err != nil {
+		return location, errors.New("Latitude '" + latitude + "' must be a number between -90 and 90")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(longitude), 64)
+	if err != nil {
+		return location, errors.New("Longitude '" + longitude + "' must be a number between -180 and 180")
+	}
+	location.Latitude = lat
+	location.Longitude = lng
+	return location, validateLocation(location)
+}
+
+//validateLocation range checks the coordinates of a location
+func validateLocation(location Location) error {
+	if math.IsNaN(location.Latitude) || location.Latitude < -90 || location.Latitude > 90 {
+		return errors.New("Latitude " + strconv.FormatFloat(location.Latitude, 'f', -1, 64) + " must be between -90 and 90")
+	}
+	if math.IsNaN(location.Longitude) || location.Longitude < -180 || location.Longitude > 180 {
+		return errors.New("Longitude " + strconv.FormatFloat(location.Longitude, 'f', -1, 64) + " must be between -180 and 180")
+	}
+	return nil
+}
+
+//UnmarshalJSON accepts numeric coordinates as well as the string coordinates stored by earlier versions
+func (l *Location) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Latitude  interface{} `json:"latitude"`
+		Longitude interface{} `json:"longitude"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	l.Latitude, err = coordinate(raw.Latitude)
+	if err != nil {
+		return errors.New("latitude: " + err.Error())
+	}
+	l.Longitude, err = coordinate(raw.Longitude)
+	if err != nil {
+		return errors.New("longitude: " + err.Error())
+	}
+	return nil
+}
+
+//coordinate converts a stored coordinate, a missing one is 0
+func coordinate(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case string:
+		return parseCoordinate(v)
+	}
+	return 0, errors.New("coordinate must be a number")
+}
+
+//parseCoordinate reads a coordinate stored as string by earlier versions, an empty one (no location) is 0
+func parseCoordinate(str string) (float64, error) {
+	str = strings.TrimSpace(str)
+	if len(str) == 0 {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, errors.New("coordinate '" + str + "' is not a number")
+	}
+	return parsed, nil
+}
+
+//distanceKm is the great circle distance between two locations
+func distanceKm(a Location, b Location) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+//checkGeofence - with a proof radius set on the project every document has to be geotagged inside it
+func checkGeofence(project Project, documents []ProofDocument) error {
+	if project.ProofRadiusKm <= 0 {
+		return nil
+	}
+	for i := range documents {
+		if documents[i].Geotag == nil {
+			return errors.New("Proof document " + fmt.Sprint(i) + " needs a geotag for project " + project.ProjectID)
+		}
+		distance := distanceKm(project.ProjectLoc, *documents[i].Geotag)
+		if distance > project.ProofRadiusKm {
+			return errors.New("Proof document " + fmt.Sprint(i) + " was taken " + strconv.FormatFloat(distance, 'f', 1, 64) + " km from the project, allowed are " + strconv.FormatFloat(project.ProofRadiusKm, 'f', 1, 64) + " km")
+		}
+	}
+	return nil
+}
+
+//getProjectsInBoxQuery fetches projects located in a bounding box, a box crossing the dateline has minLng > maxLng
+func getProjectsInBoxQuery(stub shim.ChaincodeStubInterface, minLat float64, minLng float64, maxLat float64, maxLng float64) ([]Project, error) {
+	var projects []Project
+	lngSelector := fmt.Sprintf("\"projectLoc.longitude\":{\"$gte\":%f,\"$lte\":%f}", minLng, maxLng)
+	if minLng > maxLng {
+		lngSelector = fmt.Sprintf("\"$or\":[{\"projectLoc.longitude\":{\"$gte\":%f}},{\"projectLoc.longitude\":{\"$lte\":%f}}]", minLng, maxLng)
+	}
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Project\",\"projectLoc.latitude\":{\"$gte\":%f,\"$lte\":%f},%s}}", minLat, maxLat, lngSelector)
+	projectsAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return projects, err
+	}
+	json.Unmarshal(projectsAsBytes, &projects)
+	return projects, nil
+}
+
+// ============================================================================================================================
+// getProjectsInBox() - projects located inside a bounding box
+//
+// Inputs - Array of strings
+//     0    ,    1    ,    2    ,    3
+//  minLat  , minLng  , maxLat  , maxLng
+// ============================================================================================================================
+func getProjectsInBox(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get projects in box")
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	southWest, err := parseLocation(args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	northEast, err := parseLocation(args[2], args[3])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if southWest.Latitude > northEast.Latitude {
+		return shim.Error("minLat must not be greater than maxLat")
+	}
+
+	projects, err := getProjectsInBoxQuery(stub, southWest.Latitude, southWest.Longitude, northEast.Latitude, northEast.Longitude)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	projects, err = filterVisibleProjects(stub, projects)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	projectsAsBytes, _ := json.Marshal(projects) //convert to array of bytes
+
+	log.Println("- end - get projects in box")
+	return shim.Success(projectsAsBytes)
+}
+
+// ============================================================================================================================
+// getProjectsNearby() - projects located within a radius around a point
+//
+// Inputs - Array of strings
+//     0    ,    1    ,     2
+//    lat   ,   lng   , radius in km
+// ============================================================================================================================
+func getProjectsNearby(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get projects nearby")
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	center, err := parseLocation(args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	radius, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || radius <= 0 {
+		return shim.Error("Radius must be a positive number of km")
+	}
+
+	//narrow down with a bounding box, then filter on the exact distance
+	latDelta := radius / earthRadiusKm * 180 / math.Pi
+	minLat := math.Max(-90, center.Latitude-latDelta)
+	maxLat := math.Min(90, center.Latitude+latDelta)
+	minLng, maxLng := -180.0, 180.0
+	if minLat > -90 && maxLat < 90 {
+		lngDelta := latDelta / math.Cos(center.Latitude*math.Pi/180)
+		if lngDelta < 180 {
+			minLng = center.Longitude - lngDelta
+			maxLng = center.Longitude + lngDelta
+			if minLng < -180 {
+				minLng += 360
+			}
+			if maxLng > 180 {
+				maxLng -= 360
+			}
+		}
+	}
+
+	candidates, err := getProjectsInBoxQuery(stub, minLat, minLng, maxLat, maxLng)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	candidates, err = filterVisibleProjects(stub, candidates)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	projects := []Project{}
+	for i := range candidates {
+		if distanceKm(center, candidates[i].ProjectLoc) <= radius {
+			projects = append(projects, candidates[i])
+		}
+	}
+
+	projectsAsBytes, _ := json.Marshal(projects) //convert to array of bytes
+
+	log.Println("- end - get projects nearby")
+	return shim.Success(projectsAsBytes)
+}
+
+// ============================================================================================================================
+// updateProjectGeofence() - require proofs of a project to be geotagged within a radius of the project location
+//
+// Members of the project set the radius, once the project is approved or funded only admins can change it.
+//
+// Inputs - Array of strings
+//       0     ,              1
+//   projectId , radius in km, 0 turns the rule off
+// ============================================================================================================================
+func updateProjectGeofence(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - update project geofence")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	radius, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || radius < 0 {
+		return shim.Error("Radius must be a number of km, 0 turns the rule off")
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil && !isCallerAdmin(stub, callerID) {
+		return shim.Error(err.Error())
+	}
+	//proofs of an approved or funded project keep the rule they are accepted under, only admins change it
+	if isProjectLocked(project) && radius != project.ProofRadiusKm && !isCallerAdmin(stub, callerID) {
+		return shim.Error("Project " + project.ProjectID + " is approved or funded, only admins can change its proof radius")
+	}
+
+	project.ProofRadiusKm = radius
+
+	//store project
+	project.setUpdated(callerID, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	errz := stub.PutState(project.ProjectID, projectAsBytes)
+	if errz != nil {
+		log.Println("Could not update project geofence")
+		return shim.Error(errz.Error())
+	}
+
+	log.Println("- end - update project geofence")
+	return shim.Success(nil)
+}
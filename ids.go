@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//autoID asks the chaincode to generate the id instead of the client
+const autoID = "auto"
+
+//=============== ID GENERATION RELATED FUNCTION'S START HERE ===============================================================
+
+//resolveID keeps an id chosen by the client and generates one for "auto". A chosen id must not be used by any
+//document, whatever its type
+func resolveID(stub shim.ChaincodeStubInterface, requested string, prefix string) (string, error) {
+	if requested != autoID {
+		err := checkClientID(requested)
+		if err != nil {
+			return "", err
+		}
+		used, err := isKeyUsed(stub, requested)
+		if err != nil {
+			return "", err
+		}
+		if used {
+			return "", errors.New("Id " + requested + " is already used")
+		}
+		return requested, nil
+	}
+	config, err := getConfig(stub)
+	if err != nil {
+		return "", err
+	}
+	if config.IDScheme == "txid" {
+		return nextTxID(stub, prefix)
+	}
+	return nextCounterID(stub, prefix)
+}
+
+//isKeyUsed tells whether anything is stored under the key, ids picked by clients may take generated ones
+func isKeyUsed(stub shim.ChaincodeStubInterface, key string) (bool, error) {
+	valueAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return false, errors.New("Failed to get state for " + key)
+	}
+	return len(valueAsBytes) > 0, nil
+}
+
+//nextCounterID hands out the next readable id of a prefix and year, e.g. PRJ-2026-000123.
+//Concurrent transactions generating the same kind of id conflict on the counter and have to be resubmitted.
+func nextCounterID(stub shim.ChaincodeStubInterface, prefix string) (string, error) {
+	txDate, err := getTxDate(stub)
+	if err != nil {
+		return "", err
+	}
+	year := txDate.Year()
+	counterKey := reservedKey("idCounter", prefix, strconv.Itoa(year))
+	counterAsBytes, err := stub.GetState(counterKey)
+	if err != nil {
+		return "", errors.New("Failed to get id counter of " + prefix + " " + strconv.Itoa(year))
+	}
+	counter := 0
+	if len(counterAsBytes) > 0 {
+		counter, err = strconv.Atoi(string(counterAsBytes))
+		if err != nil {
+			return "", errors.New("Id counter of " + prefix + " " + strconv.Itoa(year) + " is corrupt")
+		}
+	}
+	for {
+		counter++
+		id := fmt.Sprintf("%s-%d-%06d", prefix, year, counter)
+		used, err := isKeyUsed(stub, id)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			err = stub.PutState(counterKey, []byte(strconv.Itoa(counter)))
+			return id, err
+		}
+	}
+}
+
+//nextTxID derives the id from the transaction id, e.g. PRJ-3f9a0c1b7d2e4a56-1. It needs no shared counter,
+//the sequence only counts the ids of one prefix generated within the same transaction (batches)
+func nextTxID(stub shim.ChaincodeStubInterface, prefix string) (string, error) {
+	txID := stub.GetTxID()
+	if len(txID) > 16 {
+		txID = txID[:16]
+	}
+	for sequence := 1; ; sequence++ {
+		id := prefix + "-" + txID + "-" + strconv.Itoa(sequence)
+		used, err := isKeyUsed(stub, id)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return id, nil
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChosenIDsMustBeUnused(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+	validator := n.stub.callerID(t, n.validator)
+
+	//an id is taken by any document, whatever its type
+	expectError(t, n.stub.invoke(n.owner, "addProject", projectArgs("PRJ-1-M1", n.stub.callerID(t, n.owner), "1000")...))
+	expectError(t, n.stub.invoke(n.owner, "addProject", projectArgs(validator, n.stub.callerID(t, n.owner), "1000")...))
+	expectError(t, n.stub.invoke(n.owner, "addMilestone", milestoneArgs("PRJ-1", "PRJ-1")...))
+	expectError(t, n.stub.invoke(n.owner, "addMilestone", milestoneArgs("PRJ-1", "PRJ-1-A1")...))
+	expectError(t, n.stub.invoke(n.owner, "addActivity", activityArgs("PRJ-1", "PRJ-1-M1", "PRJ-1-M1", validator, "100")...))
+
+	if _, err := getProject(n.stub, "PRJ-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getActivity(n.stub, "PRJ-1-A1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getPrivateUser(n.stub, validator); err != nil {
+		t.Fatal("user was overwritten: " + err.Error())
+	}
+}
+
+func TestGeneratedIDsAreUnique(t *testing.T) {
+	n := newTestNetwork(t)
+	owner := n.stub.callerID(t, n.owner)
+
+	first := n.stub.invoke(n.owner, "addProject", projectArgs(autoID, owner, "1000")...)
+	expectOK(t, first)
+	second := n.stub.invoke(n.owner, "addProject", projectArgs(autoID, owner, "1000")...)
+	expectOK(t, second)
+	if !strings.HasPrefix(string(first.Payload), "PRJ-") || string(first.Payload) == string(second.Payload) {
+		t.Fatalf("generated ids %s and %s", first.Payload, second.Payload)
+	}
+}
+
+func TestDerivedDocumentsDoNotShareProjectIDs(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+	requestID := approvalRequestID("PRJ-1", 1)
+
+	expectOK(t, n.stub.invoke(n.owner, "submitProjectForReview", "PRJ-1"))
+	if _, ok := n.stub.State[requestID]; ok {
+		t.Fatal("approval request is stored under a key clients may choose")
+	}
+
+	//a client can take the readable id without touching the approval request
+	expectOK(t, n.stub.invoke(n.owner, "addProject", projectArgs(requestID, n.stub.callerID(t, n.owner), "1000")...))
+	request, err := getApprovalRequest(n.stub, requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.ProjectID != "PRJ-1" || request.Status != "Pending" {
+		t.Fatalf("approval request was changed: %+v", request)
+	}
+}
+
+func TestCloneNeedsUnusedIDs(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+	expectOK(t, n.stub.invoke(n.owner, "addProject", projectArgs("PRJ-2-M1-A1", n.stub.callerID(t, n.owner), "1000")...))
+
+	expectError(t, n.stub.invoke(n.owner, "cloneProject", "PRJ-1", "PRJ-1-M1", "Open", "cloned"))
+	expectError(t, n.stub.invoke(n.owner, "cloneProject", "PRJ-1", "PRJ-2", "Open", "cloned"))
+	if _, err := getProject(n.stub, "PRJ-2"); err == nil {
+		t.Fatal("project was cloned over a used id")
+	}
+	if _, err := getProject(n.stub, "PRJ-2-M1-A1"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectOK(t, n.stub.invoke(n.owner, "cloneProject", "PRJ-1", "PRJ-3", "Open", "cloned"))
+	if _, err := getActivity(n.stub, "PRJ-3-M1-A1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"bytes"
-	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
@@ -17,14 +18,62 @@ import (
 // ==============================================================
 // Input Sanitation - dumb input checking, look for empty strings
 // ==============================================================
-func sanitize_arguments(strs []string) error {
+func sanitize_arguments(stub shim.ChaincodeStubInterface, strs []string) error {
+	config, err := getConfig(stub)
+	if err != nil {
+		return err
+	}
+	if len(strs) > config.MaxArgCount {
+		return errors.New("At most " + strconv.Itoa(config.MaxArgCount) + " arguments are allowed")
+	}
 	for i, val := range strs {
 		if len(val) <= 0 {
 			return errors.New("Argument " + strconv.Itoa(i) + " must be a non-empty string")
 		}
-		if len(val) > 2000 {
-			return errors.New("Argument " + strconv.Itoa(i) + " must be <= 2000 characters")
+		if len(val) > config.MaxArgLength {
+			return errors.New("Argument " + strconv.Itoa(i) + " must be <= " + strconv.Itoa(config.MaxArgLength) + " characters")
 		}
+		if strings.Contains(val, compositeKeyNamespace) {
+			return errors.New("Argument " + strconv.Itoa(i) + " must not contain the null character")
+		}
+	}
+	return nil
+}
+
+//compositeKeyNamespace starts every composite key, the same separator shim.CreateCompositeKey uses
+const compositeKeyNamespace = "\x00"
+
+//reservedKey keeps internal chaincode state (configuration, counters, salts, proofs, ...) in the composite key
+//namespace, ids chosen by clients can never reach it because arguments must not contain the null character
+func reservedKey(objectType string, attributes ...string) string {
+	key := compositeKeyNamespace + objectType + compositeKeyNamespace
+	for _, attribute := range attributes {
+		key += attribute + compositeKeyNamespace
+	}
+	return key
+}
+
+//reservedDocTypes are stored under reservedKey(docType, id), their ids are derived from other ids and must not be
+//taken by a document whose id a client chose
+var reservedDocTypes = map[string]bool{
+	"ApprovalRequest": true,
+	"ChangeRequest":   true,
+	"Membership":      true,
+	"Receipt":         true,
+}
+
+//documentKey is the ledger key of a document, the id itself unless the type is one of the reservedDocTypes
+func documentKey(docType string, id string) string {
+	if reservedDocTypes[docType] {
+		return reservedKey(docType, id)
+	}
+	return id
+}
+
+//checkClientID - error unless the id may be chosen by a client, the reserved namespace is off limits
+func checkClientID(id string) error {
+	if len(id) == 0 || strings.Contains(id, compositeKeyNamespace) {
+		return errors.New("Id '" + id + "' is not allowed")
 	}
 	return nil
 }
@@ -46,38 +95,93 @@ func parseBool(str string) bool {
 }
 
 // ============================================================================================================================
-// Get User Certificate Common Name - get the seller asset from ledger
+// Get Caller ID - the identity of the caller as "<msp id>:<unique id>" from the client identity library, records of
+// users, donors and organizations are stored under it
 // ============================================================================================================================
-func get_cert(stub shim.ChaincodeStubInterface) ([]byte, error) {
-
-	creator, err := stub.GetCreator() //get the var from ledger
+func getCallerID(stub shim.ChaincodeStubInterface) (string, error) {
+	identity, err := cid.New(stub)
+	if err != nil {
+		return "", errors.New("failed to get client identity")
+	}
+	mspID, err := identity.GetMSPID()
 	if err != nil {
-		//jsonResp = "{\"Error\":\"Failed to get state for " + key + "\"}"
-		return creator, errors.New("failed to get creator")
+		return "", errors.New("failed to get MSP ID of the caller")
 	}
+	id, err := identity.GetID()
+	if err != nil {
+		return "", errors.New("failed to get ID of the caller")
+	}
+	return mspID + ":" + id, nil
+}
 
-	certStart := bytes.IndexAny(creator, "----BEGIN CERTIFICATE-----")
-	if certStart == -1 {
-		//logger.Debug("No certificate found")
-		return creator, errors.New("no certificate found")
+//getCallerAttribute reads an attribute of the caller's enrollment certificate, empty when it is not set
+func getCallerAttribute(stub shim.ChaincodeStubInterface, name string) (string, error) {
+	value, found, err := cid.GetAttributeValue(stub, name)
+	if err != nil {
+		return "", errors.New("failed to get attribute " + name + " of the caller")
 	}
-	certText := creator[certStart:]
-	block, _ := pem.Decode(certText)
-	if block == nil {
-		//logger.Debug("Error received on pem.Decode of certificate",  certText)
-		return creator, errors.New("Error received on pem.Decode of certificate")
+	if !found {
+		return "", nil
 	}
+	return value, nil
+}
 
-	ucert, err := x509.ParseCertificate(block.Bytes)
+// ============================================================================================================================
+// Get Transaction Time - the proposal timestamp of the transaction, identical on every endorser
+// ============================================================================================================================
+func getTxTime(stub shim.ChaincodeStubInterface) (string, error) {
+	txDate, err := getTxDate(stub)
 	if err != nil {
-		//logger.Debug("Error received on ParseCertificate", err)
-		return creator, errors.New("Error received on ParseCertificate")
+		return "", err
 	}
+	return txDate.Format(time.RFC3339), nil
+}
+
+//setCreated stamps a new entity with the caller and the transaction time
+func (a *Attribution) setCreated(actor string, txTime string) {
+	a.CreatedBy = actor
+	a.CreatedAt = txTime
+	a.UpdatedBy = actor
+	a.UpdatedAt = txTime
+}
 
-	fmt.Println([]byte(ucert.Subject.CommonName))
-	fmt.Println("- end read")
+//setUpdated stamps a changed entity with the caller and the transaction time
+func (a *Attribution) setUpdated(actor string, txTime string) {
+	a.UpdatedBy = actor
+	a.UpdatedAt = txTime
+}
 
-	return []byte(ucert.Subject.CommonName), nil //send it onward
+//getTransientJSON reads a JSON object handed over in the transient map of the proposal
+func getTransientJSON(stub shim.ChaincodeStubInterface, key string, v interface{}) error {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return errors.New("Failed to read transient data")
+	}
+	valueAsBytes, ok := transient[key]
+	if !ok || len(valueAsBytes) == 0 {
+		return errors.New("Expecting transient field " + key)
+	}
+	err = json.Unmarshal(valueAsBytes, v)
+	if err != nil {
+		return errors.New("Transient field " + key + " is not valid JSON")
+	}
+	return nil
+}
+
+// ============================================================================================================================
+// Get Caller Role - the role the caller registered with, if any
+// ============================================================================================================================
+func getCallerRole(stub shim.ChaincodeStubInterface, id string) string {
+	if user, err := getPrivateUser(stub, id); err == nil {
+		return user.Role
+	}
+	if donorUser, err := getDonor(stub, id); err == nil {
+		return donorUser.Role
+	}
+	if orgUser, err := getOrg(stub, id); err == nil {
+		return orgUser.Role
+	}
+	return ""
 }
 
 // =========================================================================================
@@ -114,8 +218,12 @@ func getQueryResultForQueryString(stub shim.ChaincodeStubInterface, queryString
 		buffer.WriteString("\"")
 
 		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
+		// Record is a JSON object, migrated to the current schema
+		value, _, err := migrateDocument(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString(string(value))
 		buffer.WriteString("}")
 		bArrayMemberAlreadyWritten = true
 	}
@@ -174,6 +282,53 @@ func getQueryResultInBytesForQueryStringCouch(stub shim.ChaincodeStubInterface,
 	return buffer.Bytes(), nil
 }
 
+// =========================================================================================
+// getQueryPage executes the passed in query string one page at a time and drops the records
+// the caller may not see, so a page can hold fewer records than fetched.
+// Returns {"records":[{"Key":...,"Record":...}],"bookmark":"...","fetchedRecordsCount":n}
+// =========================================================================================
+func getQueryPage(stub shim.ChaincodeStubInterface, queryString string, paging []string) ([]byte, error) {
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := config.MaxPageSize
+	bookmark := ""
+	if len(paging) > 0 {
+		pageSize, err = strconv.Atoi(paging[0])
+		if err != nil || pageSize < 1 || pageSize > config.MaxPageSize {
+			return nil, errors.New("Page size must be between 1 and " + strconv.Itoa(config.MaxPageSize))
+		}
+	}
+	if len(paging) > 1 {
+		bookmark = paging[1]
+	}
+
+	fmt.Printf("- getQueryPage queryString:\n%s\n", queryString)
+
+	resultsIterator, metadata, err := stub.GetQueryResultWithPagination(queryString, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	buffer, err := constructQueryResponseFromIterator(resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	records, err := filterVisibleResults(stub, buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	page := struct {
+		Records             json.RawMessage `json:"records"`
+		Bookmark            string          `json:"bookmark"`
+		FetchedRecordsCount int32           `json:"fetchedRecordsCount"`
+	}{records, metadata.Bookmark, metadata.FetchedRecordsCount}
+	return json.Marshal(page)
+}
+
 // =========================================================================================
 // getQueryResultForQueryStringCouch executes the passed in query string.
 // Result set is built and returned as a byte array containing the JSON results.
@@ -223,8 +378,12 @@ func constructQueryResponseFromIterator(resultsIterator shim.StateQueryIteratorI
 		buffer.WriteString("\"")
 
 		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
+		// Record is a JSON object, migrated to the current schema
+		value, _, err := migrateDocument(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString(string(value))
 		buffer.WriteString("}")
 		bArrayMemberAlreadyWritten = true
 	}
@@ -244,7 +403,7 @@ func getPrivateUser(stub shim.ChaincodeStubInterface, id string) (PrivateUser, e
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return user, errors.New("Failed to get private user - " + id)
 	}
-	json.Unmarshal(userAsBytes, &user) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &user) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(user.Username) == 0 { //test if user is actually here or just nil
 		return user, errors.New("foundation user does not exist - " + id + ", '" + user.Username + "' '")
@@ -262,10 +421,10 @@ func getDonor(stub shim.ChaincodeStubInterface, id string) (Donor, error) {
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return donorUser, errors.New("Failed to get foundation user - " + id)
 	}
-	json.Unmarshal(userAsBytes, &donorUser) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &donorUser) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(donorUser.DonorUsername) == 0 { //test if user is actually here or just nil
-		return donorUser, errors.New("foundation user does not exist - " + id + ", '" + donorUser.DonorUsername + "' '" + donorUser.DonorCompany + "'")
+		return donorUser, errors.New("foundation user does not exist - " + id + ", '" + donorUser.DonorUsername + "'")
 	}
 
 	return donorUser, nil
@@ -280,7 +439,7 @@ func getOrg(stub shim.ChaincodeStubInterface, id string) (Organization, error) {
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return organizationUser, errors.New("Failed to get organization user - " + id)
 	}
-	json.Unmarshal(userAsBytes, &organizationUser) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &organizationUser) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(organizationUser.OrgUsername) == 0 { //test if user is actually here or just nil
 		return organizationUser, errors.New("organization does not exist - " + id + ", '" + organizationUser.OrgUsername + "' '" + organizationUser.OrgCompany + "'")
@@ -291,13 +450,15 @@ func getOrg(stub shim.ChaincodeStubInterface, id string) (Organization, error) {
 
 // =================================================================================================
 // query_all_invoice - Query records using a (partial) composite key named by first argument
+//
+// Inputs - anything, docType, page size (optional, at most maxPageSize of the configuration), bookmark (optional)
 // =================================================================================================
 func query_all(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 	log.Println("***********Entering query_invoice_by_status***********")
-	// if len(args) < 1 {
-	// 	return shim.Error("Incorrect number of arguments. Expecting 1")
-	// }
+	if len(args) < 2 {
+		return shim.Error("Incorrect number of arguments. Expecting at least 2")
+	}
 	log.Println(args[1])
 
 	// docType := strings.Replace(args[1], "\"", "", -1)
@@ -305,7 +466,7 @@ func query_all(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	docType := args[1]
 	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"%s\"}}", docType)
 
-	queryResults, err := getQueryResultInBytesForQueryStringCouch(stub, queryString)
+	queryResults, err := getQueryPage(stub, queryString, args[2:])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -361,8 +522,12 @@ func myFunctions(resultsIterator shim.StateQueryIteratorInterface) (*bytes.Buffe
 		// buffer.WriteString("\"")
 
 		// buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
+		// Record is a JSON object, migrated to the current schema
+		value, _, err := migrateDocument(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString(string(value))
 		// buffer.WriteString("}")
 		bArrayMemberAlreadyWritten = true
 	}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== MEMBERSHIP RELATED FUNCTION'S START HERE ====================================================================
+
+//membershipID is the id of the membership of a user in an organization, see documentKey for its ledger key
+func membershipID(orgID string, userID string) string {
+	return orgID + "_" + userID
+}
+
+// ============================================================================================================================
+// Get Membership - get the membership of a user in an organization from ledger
+// ============================================================================================================================
+func getMembership(stub shim.ChaincodeStubInterface, orgID string, userID string) (Membership, error) {
+	var membership Membership
+	id := membershipID(orgID, userID)
+	membershipAsBytes, err := stub.GetState(documentKey("Membership", id)) //getState retreives a key/value from the ledger
+	if err != nil {                                                        //this seems to always succeed, even if key didn't exist
+		return membership, errors.New("Failed to get membership - " + id)
+	}
+	unmarshalDocument(membershipAsBytes, &membership) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(membership.MembershipID) == 0 { //test if membership is actually here or just nil
+		return membership, errors.New("membership does not exist - " + id)
+	}
+
+	return membership, nil
+}
+
+//isOrgMember tells whether the user acts for the organization, the organization identity itself always does
+func isOrgMember(stub shim.ChaincodeStubInterface, orgID string, userID string) bool {
+	if orgID == userID {
+		return true
+	}
+	membership, err := getMembership(stub, orgID, userID)
+	return err == nil && membership.Status == "Active"
+}
+
+//isOrgAdmin tells whether the user may manage the members of the organization
+func isOrgAdmin(stub shim.ChaincodeStubInterface, orgID string, userID string) bool {
+	if orgID == userID {
+		return true
+	}
+	membership, err := getMembership(stub, orgID, userID)
+	return err == nil && membership.Status == "Active" && membership.Role == "admin"
+}
+
+//parseProjectOrgs turns a JSON array of organization ids into the organization lists of a project,
+//every id has to be a registered organization the caller acts for, unless it is already listed on the project
+func parseProjectOrgs(stub shim.ChaincodeStubInterface, orgString string, caller string, listed []projectOrg) ([]projectOrg, []ngoCompany, error) {
+	var orglist []string
+	err := json.NewDecoder(strings.NewReader(orgString)).Decode(&orglist)
+	if err != nil {
+		return nil, nil, errors.New("Organizations must be a JSON array of organization ids")
+	}
+	var projOrg []projectOrg
+	var ngoComp []ngoCompany
+	for i := range orglist {
+		org, err := getOrg(stub, orglist[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isOrgMember(stub, org.OrgID, caller) && !isListedOrg(listed, org.OrgID) {
+			return nil, nil, errors.New(caller + " is not a member of organization " + org.OrgID)
+		}
+		projOrg = append(projOrg, projectOrg{OrgID: org.OrgID, OrgName: org.OrgCompany})
+		ngoComp = append(ngoComp, ngoCompany{OrgID: org.OrgID, OrgName: org.OrgCompany})
+	}
+	return projOrg, ngoComp, nil
+}
+
+//isListedOrg tells whether the organization is one of the organizations of a project
+func isListedOrg(listed []projectOrg, orgID string) bool {
+	for _, org := range listed {
+		if org.OrgID == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+//checkProjectMember - the caller has to own the project, have created it or act for one of its organizations
+func checkProjectMember(stub shim.ChaincodeStubInterface, project Project, caller string) error {
+	if caller == project.ProjectOwner || caller == project.CreatedBy {
+		return nil
+	}
+	for _, org := range project.Organization {
+		if len(org.OrgID) > 0 && isOrgMember(stub, org.OrgID, caller) {
+			return nil
+		}
+	}
+	return errors.New(caller + " is not a member of any organization of project " + project.ProjectID)
+}
+
+// ============================================================================================================================
+// inviteMember() - invite a user to act for an organization
+//
+// Only the organization itself or one of its active admins can invite. A revoked user can be invited again.
+//
+// Inputs - Array of strings
+//    0   ,    1   ,  2
+//  orgId , userId , role    e.g. admin, member
+// ============================================================================================================================
+func inviteMember(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - invite member")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	org, err := getOrg(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isOrgAdmin(stub, org.OrgID, callerID) {
+		return shim.Error("Only the organization or one of its admins can invite members")
+	}
+	if len(getCallerRole(stub, args[1])) == 0 {
+		return shim.Error("User is not registered - " + args[1])
+	}
+
+	membership, err := getMembership(stub, org.OrgID, args[1])
+	if err == nil && membership.Status != "Revoked" {
+		return shim.Error("User " + args[1] + " is already " + membership.Status + " in organization " + org.OrgID)
+	}
+	if err != nil {
+		membership.ObjectType = "Membership"
+		membership.SchemaVersion = currentSchemaVersion
+		membership.MembershipID = membershipID(org.OrgID, args[1])
+		membership.OrgID = org.OrgID
+		membership.UserID = args[1]
+		membership.setCreated(callerID, txTime)
+	}
+	membership.Role = args[2]
+	membership.Status = "Invited"
+	membership.InvitedBy = callerID
+	membership.InvitedAt = txTime
+	membership.AcceptedAt = ""
+	membership.RevokedBy = ""
+	membership.RevokedAt = ""
+
+	//store membership
+	membership.setUpdated(callerID, txTime)
+	membershipAsBytes, _ := json.Marshal(membership) //convert to array of bytes
+	err = stub.PutState(documentKey("Membership", membership.MembershipID), membershipAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - invite member")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// acceptMembership() - the invited user accepts to act for the organization
+//
+// Inputs - Array of strings
+//    0
+//  orgId
+// ============================================================================================================================
+func acceptMembership(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - accept membership")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	membership, err := getMembership(stub, args[0], callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if membership.Status != "Invited" {
+		return shim.Error("Membership " + membership.MembershipID + " is " + membership.Status + ", expecting Invited")
+	}
+
+	membership.Status = "Active"
+	membership.AcceptedAt = txTime
+
+	//store membership
+	membership.setUpdated(callerID, txTime)
+	membershipAsBytes, _ := json.Marshal(membership) //convert to array of bytes
+	err = stub.PutState(documentKey("Membership", membership.MembershipID), membershipAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - accept membership")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// revokeMembership() - end the membership of a user, done by the organization, one of its admins or the user leaving
+//
+// Inputs - Array of strings
+//    0   ,    1
+//  orgId , userId
+// ============================================================================================================================
+func revokeMembership(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - revoke membership")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	membership, err := getMembership(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerID != membership.UserID && !isOrgAdmin(stub, membership.OrgID, callerID) {
+		return shim.Error("Only the organization, one of its admins or the member can revoke a membership")
+	}
+	if membership.Status == "Revoked" {
+		return shim.Error("Membership " + membership.MembershipID + " is already revoked")
+	}
+
+	membership.Status = "Revoked"
+	membership.RevokedBy = callerID
+	membership.RevokedAt = txTime
+
+	//store membership
+	membership.setUpdated(callerID, txTime)
+	membershipAsBytes, _ := json.Marshal(membership) //convert to array of bytes
+	err = stub.PutState(documentKey("Membership", membership.MembershipID), membershipAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - revoke membership")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// getMemberships() - list the memberships of an organization or of a user
+//
+// Inputs - Array of strings
+//         0       ,  1
+//  "org" | "user" , id
+// ============================================================================================================================
+func getMemberships(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get memberships")
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var field string
+	if args[0] == "org" {
+		field = "orgId"
+	} else if args[0] == "user" {
+		field = "userId"
+	} else {
+		return shim.Error("Scope must be org or user")
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Membership\",\"%s\":\"%s\"}}", field, args[1])
+	membershipsAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - get memberships")
+	return shim.Success(membershipsAsBytes)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//currentSchemaVersion is the schemaVersion of every document written by this chaincode,
+//documents without schemaVersion are version 0
+const currentSchemaVersion = 1
+
+//=============== MIGRATION RELATED FUNCTION'S START HERE =====================================================================
+
+//migration upgrades a stored document to the given schema version
+type migration struct {
+	version     int      // schema version of the document afterwards
+	docTypes    []string // document types it applies to, empty for every type
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+//migrations is the registry of all migrations, in the order they are applied. Add new ones at the end
+//together with an increment of currentSchemaVersion, never change a released one.
+var migrations = []migration{
+	{1, nil, "coordinates stored as strings become numbers", migrateLocations},
+	{1, []string{"Milestone"}, "MilFundReleased is renamed to milFundReleased", renameField("MilFundReleased", "milFundReleased")},
+	{1, []string{"Project"}, "free text Beneficiaries are dropped in favour of registered beneficiaryIds", migrateBeneficiaries},
+	{1, []string{"Activity"}, "the single validatorPanel becomes the technical and the financial panel", migrateValidatorPanel},
+}
+
+func (m migration) appliesTo(docType string) bool {
+	if len(m.docTypes) == 0 {
+		return true
+	}
+	for _, t := range m.docTypes {
+		if t == docType {
+			return true
+		}
+	}
+	return false
+}
+
+//renameField moves a value to a new field name, an existing value under the new name wins
+func renameField(from string, to string) func(doc map[string]interface{}) error {
+	return func(doc map[string]interface{}) error {
+		value, ok := doc[from]
+		if !ok {
+			return nil
+		}
+		delete(doc, from)
+		if _, exists := doc[to]; !exists {
+			doc[to] = value
+		}
+		return nil
+	}
+}
+
+//migrateLocations converts {"latitude":"1.5","longitude":"2"} into numbers, empty coordinates (no location) become 0
+//and unparsable ones stop the migration, the document has to be corrected first
+func migrateLocations(doc map[string]interface{}) error {
+	for field, value := range doc {
+		location, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"latitude", "longitude"} {
+			if str, ok := location[key].(string); ok {
+				parsed, err := parseCoordinate(str)
+				if err != nil {
+					return errors.New(field + ": " + err.Error())
+				}
+				location[key] = parsed
+			}
+		}
+	}
+	return nil
+}
+
+//migrateBeneficiaries drops the names of the old Beneficiaries list, they were personal data on the public ledger
+//and cannot be turned into registered beneficiaries without their identifiers
+func migrateBeneficiaries(doc map[string]interface{}) error {
+	delete(doc, "Beneficiaries")
+	if _, ok := doc["beneficiaryIds"]; !ok {
+		doc["beneficiaryIds"] = []interface{}{}
+	}
+	return nil
+}
+
+//migrateValidatorPanel copies the old combined panel into both tracks, its votes covered both aspects
+func migrateValidatorPanel(doc map[string]interface{}) error {
+	panel, ok := doc["validatorPanel"]
+	if !ok {
+		return nil
+	}
+	delete(doc, "validatorPanel")
+	for _, track := range []string{"technicalValidation", "financialValidation"} {
+		if existing, ok := doc[track].(map[string]interface{}); ok {
+			if validators, ok := existing["validators"].([]interface{}); ok && len(validators) > 0 {
+				continue
+			}
+		}
+		doc[track] = panel
+	}
+	return nil
+}
+
+//migrateDocument brings a stored JSON document to the current schema version,
+//anything that is not a JSON object (missing keys, counters, ...) is returned unchanged
+func migrateDocument(value []byte) ([]byte, bool, error) {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return value, false, nil
+	}
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return value, false, nil
+	}
+	version := 0
+	if number, ok := doc["schemaVersion"].(json.Number); ok {
+		parsed, err := number.Int64()
+		if err == nil {
+			version = int(parsed)
+		}
+	}
+	if version >= currentSchemaVersion {
+		return value, false, nil
+	}
+	docType, _ := doc["docType"].(string)
+	for _, m := range migrations {
+		if m.version > version && m.appliesTo(docType) {
+			err = m.apply(doc)
+			if err != nil {
+				return value, false, err
+			}
+		}
+	}
+	doc["schemaVersion"] = currentSchemaVersion
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return value, false, err
+	}
+	return migrated, true, nil
+}
+
+//unmarshalDocument migrates a stored document on read before parsing it
+func unmarshalDocument(value []byte, v interface{}) error {
+	migrated, _, err := migrateDocument(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(migrated, v)
+}
+
+// ============================================================================================================================
+// migrateData() - an admin rewrites up to batchSize documents of a type that are still on an older schema version
+//
+// Call it repeatedly until "remaining" is false. Documents are migrated on read anyway, this makes the world state
+// and CouchDB queries consistent. Private data collections are migrated on read only.
+//
+// Inputs - Array of strings
+//     0    ,     1
+//  docType , batchSize
+//
+// Returns - {"migrated":n,"remaining":true|false}
+// ============================================================================================================================
+func migrateData(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - migrate data")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if !isCallerAdmin(stub, callerID) {
+		return shim.Error("Only admins can migrate data")
+	}
+	//the document types of read() and the internal ones kept in the world state
+	if !readableTypes[args[0]] && args[0] != "Proof" && args[0] != "Config" {
+		return shim.Error("Unknown document type '" + args[0] + "'")
+	}
+	batchSize, err := strconv.Atoi(args[1])
+	if err != nil || batchSize < 1 {
+		return shim.Error("Batch size must be a positive number")
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"%s\",\"$or\":[{\"schemaVersion\":{\"$exists\":false}},{\"schemaVersion\":{\"$lt\":%d}}]}}", args[0], currentSchemaVersion)
+	resultsIterator, err := stub.GetQueryResult(queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	migratedCount := 0
+	for resultsIterator.HasNext() && migratedCount < batchSize {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		migrated, changed, err := migrateDocument(queryResponse.Value)
+		if err != nil {
+			return shim.Error("Document " + queryResponse.Key + " can not be migrated: " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+		err = stub.PutState(queryResponse.Key, migrated)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		migratedCount++
+	}
+	remaining := resultsIterator.HasNext()
+
+	log.Println("- end - migrate data")
+	return shim.Success([]byte("{\"migrated\":" + strconv.Itoa(migratedCount) + ",\"remaining\":" + strconv.FormatBool(remaining) + "}"))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -18,32 +19,46 @@ func addProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - project creation")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 24 {
 		return shim.Error("Incorrect number of arguments. Expecting 24")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validate the schedule
+	err = validateSchedule(args[6], args[7])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	//set project details
 	var project Project
-	project.ProjectID = args[0]
+	project.ProjectID, err = resolveID(stub, args[0], "PRJ")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 
 	// get the project
 	proj, err := getProject(stub, project.ProjectID)
 	if err == nil {
 		fmt.Println("Project is already present " + proj.ProjectID)
-		return shim.Error(err.Error())
+		return shim.Error("Project is already present " + proj.ProjectID)
 	}
 
 	sdgString := args[18]
@@ -59,36 +74,29 @@ func addProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		sdg = append(sdg, s)
 	}
 
-	beneficiaryString := args[23]
-	var beneficiarylist []string
-	bens := json.NewDecoder(strings.NewReader(beneficiaryString))
-	benerrrs := bens.Decode(&beneficiarylist)
-	log.Println(benerrrs, beneficiarylist)
-	var beneficiaryNames []Beneficiaries
-	for i := range beneficiarylist {
-		var b Beneficiaries
-		b.Beneficiary = beneficiarylist[i]
-		beneficiaryNames = append(beneficiaryNames, b)
-	}
-
-	orgString := args[13]
-	var orglist []string
-	decs := json.NewDecoder(strings.NewReader(orgString))
-	errrs := decs.Decode(&orglist)
-	log.Println(errrs, orglist)
-	log.Println(decs.Decode(&orglist))
-	var ngoComp []ngoCompany
-	var projOrg []projectOrg
-	for i := range orglist {
-		var n ngoCompany
-		var o projectOrg
-		n.OrgName = orglist[i]
-		o.OrgName = orglist[i]
-		ngoComp = append(ngoComp, n)
-		projOrg = append(projOrg, o)
+	//projects only reference registered beneficiaries
+	beneficiaryIDs, err := parseBeneficiaryIDs(stub, args[23], nil)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkCurrency(stub, args[9])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAllocationType(stub, args[14])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//organizations are referenced by their ids
+	projOrg, ngoComp, err := parseProjectOrgs(stub, args[13], callerID, nil)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
 	project.ObjectType = "Project"
+	project.SchemaVersion = currentSchemaVersion
 	project.Organization = projOrg
 	project.NGOCompany = ngoComp
 	project.ProjectName = args[3]
@@ -98,27 +106,29 @@ func addProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	project.EndDate = args[7]
 	project.Description = args[8]
 	project.Currency = args[9]
-	project.FundRaised = parseFloat(args[10])
-	project.FundAllocated = parseFloat(args[11])
+	//args[10] fundRaised, args[11] fundAllocated and args[22] fundNotAllocated are ignored, only donations and
+	//allocations change the funds
 	project.ProjectBudget = parseFloat(args[12])
 	project.FundAllocationType = args[14]
-	project.IsPublished = parseBool(args[15])
+	//args[15] isPublished is ignored, projects are published through the review workflow, see approval.go
 	project.Status = args[16]
 	project.Flag = args[17]
 	project.SDG = sdg
 	project.ProjectOwner = args[1]
 
-	var location Location
-	location.Latitude = args[19]
-	location.Longitude = args[20]
+	location, err := parseLocation(args[19], args[20])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	project.Country = args[21]
-	project.FundNotAllocated = parseFloat(args[22])
-	project.Beneficiaries = beneficiaryNames
+	project.BeneficiaryIDs = beneficiaryIDs
 	project.ProjectLoc = location
 	project.Visibility = "Just Me"
+	project.SubRole = getCallerRole(stub, callerID)
 	log.Println("project object is creataed ", project)
 
 	//store project
+	project.setCreated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -128,7 +138,7 @@ func addProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	log.Println("- end - Project creation")
-	return shim.Success(nil)
+	return shim.Success([]byte(project.ProjectID))
 }
 
 //updateProject
@@ -136,19 +146,24 @@ func updateProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	var err error
 	log.Println("starting - update project")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 24 {
 		return shim.Error("Incorrect number of arguments. Expecting 24")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -160,6 +175,28 @@ func updateProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validate the schedule, existing milestones must still fit into it
+	err = validateSchedule(args[6], args[7])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	milestones, err := getMilestonesByProject(stub, project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for i := range milestones {
+		err = validateWithin(milestones[i].StartDate, milestones[i].EndDate, args[6], args[7], "project")
+		if err != nil {
+			return shim.Error("Milestone " + milestones[i].MilestoneID + ": " + err.Error())
+		}
+	}
+
 	sdgString := args[18]
 	var list []string
 	dec := json.NewDecoder(strings.NewReader(sdgString))
@@ -173,36 +210,30 @@ func updateProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		sdg = append(sdg, s)
 	}
 
-	beneficiaryString := args[23]
-	var beneficiarylist []string
-	bens := json.NewDecoder(strings.NewReader(beneficiaryString))
-	benerrrs := bens.Decode(&beneficiarylist)
-	log.Println(benerrrs, beneficiarylist)
-	var beneficiaryNames []Beneficiaries
-	for i := range beneficiarylist {
-		var b Beneficiaries
-		b.Beneficiary = beneficiarylist[i]
-		beneficiaryNames = append(beneficiaryNames, b)
-	}
-	fmt.Println(beneficiaryNames)
-	fmt.Println(beneficiaryString)
-	orgString := args[13]
-	var orglist []string
-	decs := json.NewDecoder(strings.NewReader(orgString))
-	errrs := decs.Decode(&orglist)
-	log.Println(errrs, orglist)
-	log.Println(decs.Decode(&orglist))
-	var ngoComp []ngoCompany
-	var projOrg []projectOrg
-	for i := range orglist {
-		var n ngoCompany
-		var o projectOrg
-		n.OrgName = orglist[i]
-		o.OrgName = orglist[i]
-		ngoComp = append(ngoComp, n)
-		projOrg = append(projOrg, o)
+	//projects only reference registered beneficiaries
+	beneficiaryIDs, err := parseBeneficiaryIDs(stub, args[23], project.BeneficiaryIDs)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkCurrency(stub, args[9])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAllocationType(stub, args[14])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//organizations are referenced by their ids
+	projOrg, ngoComp, err := parseProjectOrgs(stub, args[13], callerID, project.Organization)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
+	locked := isProjectLocked(project)
+	before := amendableValues(projectAmendable(&project))
+
 	project.Organization = projOrg
 	project.NGOCompany = ngoComp
 	project.ProjectName = args[3]
@@ -212,28 +243,38 @@ func updateProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	project.EndDate = args[7]
 	project.Description = args[8]
 	project.Currency = args[9]
-	project.FundRaised = parseFloat(args[10])
-	project.FundAllocated = parseFloat(args[11])
+	//args[10] fundRaised, args[11] fundAllocated and args[22] fundNotAllocated are ignored, only donations and
+	//allocations change the funds
 	project.ProjectBudget = parseFloat(args[12])
 
 	project.FundAllocationType = args[14]
-	project.IsPublished = parseBool(args[15])
+	//args[15] isPublished is ignored, projects are published through the review workflow, see approval.go
 	project.Status = args[16]
 	project.Flag = args[17]
 	project.SDG = sdg
+	if locked && args[1] != project.ProjectOwner {
+		return shim.Error("The owner of a project can not be changed once it is approved or funded")
+	}
 	project.ProjectOwner = args[1]
 
-	var location Location
-	location.Latitude = args[19]
-	location.Longitude = args[20]
+	location, err := parseLocation(args[19], args[20])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	project.Country = args[21]
-	project.FundNotAllocated = parseFloat(args[22])
-	project.Beneficiaries = beneficiaryNames
+	project.BeneficiaryIDs = beneficiaryIDs
 	project.ProjectLoc = location
 
+	//budget and dates of approved or funded projects change through change requests, see amendment.go
+	err = checkAmendments(locked, before, projectAmendable(&project))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	log.Println("update project object is creataed ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -251,19 +292,24 @@ func updateProjectStatus(stub shim.ChaincodeStubInterface, args []string) pb.Res
 	var err error
 	log.Println("starting - update Project project status and flag")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
-	if len(args) != 6 {
-		return shim.Error("Incorrect number of arguments. Expecting 6")
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -275,15 +321,21 @@ func updateProjectStatus(stub shim.ChaincodeStubInterface, args []string) pb.Res
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil && !isCallerAdmin(stub, callerID) {
+		return shim.Error(err.Error())
+	}
+
+	//approval and publishing go through the review workflow, see approval.go
 	project.Status = args[1]
 	project.Flag = args[2]
-	project.IsPublished = parseBool(args[3])
-	project.IsApproved = parseBool(args[4])
-	project.Remarks = args[5]
+	project.Remarks = args[3]
 
 	log.Println("update Project project status and flag object is creataed ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -301,19 +353,24 @@ func updateProjectVisibility(stub shim.ChaincodeStubInterface, args []string) pb
 	var err error
 	log.Println("starting - update project visibility")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 2 {
 		return shim.Error("Incorrect number of arguments. Expecting 2")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -325,11 +382,22 @@ func updateProjectVisibility(stub shim.ChaincodeStubInterface, args []string) pb
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = validateVisibility(args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	project.Visibility = args[1]
 
 	log.Println("update project visibility ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -348,19 +416,19 @@ func deleteProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	var err error
 	log.Println("starting - delete project")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
 	if len(args) != 1 {
 		return shim.Error("Incorrect number of arguments. Expecting 1")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -372,6 +440,17 @@ func deleteProject(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//approved or funded projects stay on the ledger
+	if isProjectLocked(project) {
+		return shim.Error("Project is locked once approved or funded and can no longer be deleted")
+	}
+
 	log.Println("delete project ", project)
 
 	err = stub.DelState(args[0]) //remove the key from chaincode state
@@ -391,29 +470,39 @@ func addMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - milestone creation")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 10 {
 		return shim.Error("Incorrect number of arguments. Expecting 10")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	milestoneID, err := resolveID(stub, args[1], "MIL")
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	// check the milestone
-	mil, err := getMilestone(stub, args[1])
+	mil, err := getMilestone(stub, milestoneID)
 
 	if err == nil {
 		fmt.Println("MilestoneID is already present " + mil.MilestoneID)
-		return shim.Error(err.Error())
+		return shim.Error("Milestone is already present " + mil.MilestoneID)
 	}
 
 	// get the project
@@ -423,22 +512,40 @@ func addMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkStructureLocked(isProjectLocked(project), "Project "+project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//milestone has to be scheduled within the project
+	err = validateWithin(args[3], args[4], project.StartDate, project.EndDate, "project")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	var milestone Milestone
 
 	milestone.ObjectType = "Milestone"
+	milestone.SchemaVersion = currentSchemaVersion
 	milestone.ProjectID = args[0]
-	milestone.MilestoneID = args[1]
+	milestone.MilestoneID = milestoneID
 	milestone.MilestoneName = args[2]
 	milestone.StartDate = args[3]
 	milestone.EndDate = args[4]
 	milestone.Description = args[5]
 	milestone.Status = args[6]
-	milestone.IsApproved = parseBool(args[7])
+	//args[7] isApproved is ignored, milestones are approved with their project, see approval.go
 
 	project.Status = args[8]
 	project.Flag = args[9]
 
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -448,6 +555,7 @@ func addMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//store project
+	milestone.setCreated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -457,7 +565,7 @@ func addMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 	log.Println("- end - milestone creation")
 
-	return shim.Success(nil)
+	return shim.Success([]byte(milestone.MilestoneID))
 }
 
 //updateMilestone
@@ -465,19 +573,24 @@ func updateMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Respons
 	var err error
 	log.Println("starting - updatae milestone")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 8 {
 		return shim.Error("Incorrect number of arguments. Expecting 8")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -497,16 +610,48 @@ func updateMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Respons
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//milestone has to be scheduled within the project, existing activities within the milestone
+	err = validateWithin(args[2], args[3], project.StartDate, project.EndDate, "project")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	activities, err := getActivitiesByMilestone(stub, milestone.MilestoneID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for i := range activities {
+		err = validateWithin(activities[i].StartDate, activities[i].EndDate, args[2], args[3], "milestone")
+		if err != nil {
+			return shim.Error("Activity " + activities[i].ActivityID + ": " + err.Error())
+		}
+	}
+
+	locked := isMilestoneLocked(project, milestone)
+	before := amendableValues(milestoneAmendable(&milestone))
+
 	milestone.MilestoneName = args[1]
 	milestone.StartDate = args[2]
 	milestone.EndDate = args[3]
 	milestone.Description = args[4]
 	milestone.Status = args[5]
 
+	//budget and dates of approved or funded milestones change through change requests, see amendment.go
+	err = checkAmendments(locked, before, milestoneAmendable(&milestone))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	project.Status = args[6]
 	project.Flag = args[7]
 
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -516,6 +661,7 @@ func updateMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Respons
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -533,19 +679,24 @@ func updateMilestoneStatus(stub shim.ChaincodeStubInterface, args []string) pb.R
 	var err error
 	log.Println("starting - update milestone status")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
-	if len(args) != 6 {
-		return shim.Error("Incorrect number of arguments. Expecting 6")
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 5")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -565,18 +716,24 @@ func updateMilestoneStatus(stub shim.ChaincodeStubInterface, args []string) pb.R
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	// upate milestone
 	milestone.Status = args[1]
-	milestone.IsApproved = parseBool(args[2])
+	//args[2] isApproved is ignored, milestones are approved with their project, see approval.go
 
 	// update project
 	project.Status = args[3]
 	project.Flag = args[4]
-	project.IsApproved = parseBool(args[5])
 
 	log.Println("update milestone status object is creataed ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -586,6 +743,7 @@ func updateMilestoneStatus(stub shim.ChaincodeStubInterface, args []string) pb.R
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errm != nil {
@@ -603,19 +761,19 @@ func deleteMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Respons
 	var err error
 	log.Println("starting - delete milestone")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
 	if len(args) != 1 {
 		return shim.Error("Incorrect number of arguments. Expecting 1")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -627,6 +785,23 @@ func deleteMilestone(stub shim.ChaincodeStubInterface, args []string) pb.Respons
 		return shim.Error(err.Error())
 	}
 
+	// get the project
+	project, err := getProject(stub, milestone.ProjectID)
+	if err != nil {
+		fmt.Println("Project is missing " + milestone.ProjectID)
+		return shim.Error(err.Error())
+	}
+
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkStructureLocked(isMilestoneLocked(project, milestone), "Milestone "+milestone.MilestoneID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	log.Println("delete milestone ", milestone)
 
 	err = stub.DelState(args[0]) //remove the key from chaincode state
@@ -646,28 +821,44 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - activity creation")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 18 {
 		return shim.Error("Incorrect number of arguments. Expecting 18")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[12])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	activityID, err := resolveID(stub, args[2], "ACT")
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	// check the milestone
-	act, err := getActivity(stub, args[2])
+	act, err := getActivity(stub, activityID)
 	if err == nil {
-		fmt.Println("ActivityID is already present " + act.MilestoneID)
-		return shim.Error(err.Error())
+		fmt.Println("ActivityID is already present " + act.ActivityID)
+		return shim.Error("Activity is already present " + act.ActivityID)
 	}
 
 	// get the milestone
@@ -677,6 +868,12 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//activity has to be scheduled within the milestone
+	err = validateWithin(args[4], args[5], milestone.StartDate, milestone.EndDate, "milestone")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	// get the project
 	project, err := getProject(stub, args[0])
 	if err != nil {
@@ -684,12 +881,26 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if milestone.ProjectID != project.ProjectID {
+		return shim.Error("Milestone " + milestone.MilestoneID + " does not belong to project " + project.ProjectID)
+	}
+	err = checkStructureLocked(isMilestoneLocked(project, milestone), "Milestone "+milestone.MilestoneID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	var activity Activity
 
 	activity.ObjectType = "Activity"
+	activity.SchemaVersion = currentSchemaVersion
 	activity.ProjectID = args[0]
 	activity.MilestoneID = args[1]
-	activity.ActivityID = args[2]
+	activity.ActivityID = activityID
 	activity.ActivityName = args[3]
 	activity.StartDate = args[4]
 	activity.EndDate = args[5]
@@ -697,8 +908,16 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	activity.Description = args[7]
 	activity.SecondaryValidation = parseBool(args[8])
 	activity.Remarks = args[9]
-	activity.IsApproved = parseBool(args[10])
+	//args[10] isApproved is ignored, activities are approved with their project, see approval.go
 	activity.ValidatorID = args[11]
+	err = seedValidatorPanel(&activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkActivityValidators(stub, project, &activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	activity.Status = args[12]
 	activity.TechnicalCriteria = args[13]
 	activity.FinancialCriteria = args[14]
@@ -709,6 +928,7 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	project.Status = args[16]
 	project.Flag = args[17]
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -718,6 +938,7 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//update milestone
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -726,6 +947,7 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//update activity
+	activity.setCreated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -735,7 +957,7 @@ func addActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 	log.Println("- end - activity creation")
 
-	return shim.Success(nil)
+	return shim.Success([]byte(activity.ActivityID))
 }
 
 //updateActivity
@@ -743,19 +965,30 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	var err error
 	log.Println("starting - activity creation")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 16 {
 		return shim.Error("Incorrect number of arguments. Expecting 16")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[10])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -774,6 +1007,12 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		return shim.Error(err.Error())
 	}
 
+	//activity has to be scheduled within the milestone
+	err = validateWithin(args[2], args[3], milestone.StartDate, milestone.EndDate, "milestone")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	// get the project
 	project, err := getProject(stub, activity.ProjectID)
 	if err != nil {
@@ -781,15 +1020,49 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	locked := isActivityLocked(project, activity)
+	before := amendableValues(activityAmendable(&activity))
+
 	activity.ActivityName = args[1]
 	activity.StartDate = args[2]
 	activity.EndDate = args[3]
 	activity.ActivityBudget = parseFloat(args[4])
+
+	//budget and dates of approved or funded activities change through change requests, see amendment.go
+	err = checkAmendments(locked, before, activityAmendable(&activity))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	activity.Description = args[5]
 	activity.SecondaryValidation = parseBool(args[6])
 	activity.Remarks = args[7]
-	activity.IsApproved = parseBool(args[8])
+	//args[8] isApproved is ignored, activities are approved with their project, see approval.go
+	validatorChanged := args[9] != activity.ValidatorID
+	if validatorChanged {
+		if len(activity.TechnicalValidation.Votes) > 0 || len(activity.FinancialValidation.Votes) > 0 {
+			return shim.Error("Validation of activity " + activity.ActivityID + " has already started, the validator can not be changed")
+		}
+		activity.TechnicalValidation = ValidationPanel{}
+		activity.FinancialValidation = ValidationPanel{}
+	}
 	activity.ValidatorID = args[9]
+	err = seedValidatorPanel(&activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if validatorChanged {
+		err = checkActivityValidators(stub, project, &activity)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
 	activity.Status = args[10]
 	activity.TechnicalCriteria = args[11]
 	activity.FinancialCriteria = args[12]
@@ -801,6 +1074,7 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	project.Flag = args[15]
 
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -810,6 +1084,7 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	}
 
 	//update milestone
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -818,6 +1093,7 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	}
 
 	//update activity
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -830,35 +1106,94 @@ func updateActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	return shim.Success(nil)
 }
 
+//updateActivityValidation - the caller votes on every validation track of an activity whose panel lists the caller
+//
+// Inputs - activityId, "Validation Successful" to approve anything else rejects
 func updateActivityValidation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - update activity validation status")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 2 {
 		return shim.Error("Incorrect number of arguments. Expecting 2")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	// check the activity
-	activity, err := getActivity(stub, args[0])
+	return castActivityValidation(stub, callerID, txTime, args[0], "", args[1], "", nil, "")
+}
+
+//voteActivityValidation - the caller votes on the technical or the financial track of an activity
+//
+// Inputs - activityId, technical|financial, "Validation Successful"|"Approved" to approve anything else rejects,
+//          remarks (optional), evidence references as JSON array (optional), proof version (optional, has to be the latest)
+func voteActivityValidation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - vote activity validation")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Println("ActivityID is not present " + activity.ActivityID)
 		return shim.Error(err.Error())
 	}
+
+	if len(args) < 3 || len(args) > 6 {
+		return shim.Error("Incorrect number of arguments. Expecting 3 to 6")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
 	if err != nil {
-		fmt.Println("Milestone is not present " + activity.MilestoneID)
+		return shim.Error(err.Error())
+	}
+
+	var remarks string
+	var evidence []string
+	var proofVersion string
+	if len(args) >= 4 {
+		remarks = args[3]
+	}
+	if len(args) >= 5 {
+		err = json.Unmarshal([]byte(args[4]), &evidence)
+		if err != nil {
+			return shim.Error("Expecting a JSON array of evidence references")
+		}
+	}
+	if len(args) == 6 {
+		proofVersion = args[5]
+	}
+
+	return castActivityValidation(stub, callerID, txTime, args[0], args[1], args[2], remarks, evidence, proofVersion)
+}
+
+//castActivityValidation records the vote of the caller on one track, on every track whose panel lists the caller
+//when no track is named, and derives the activity status from the outcome of the panels
+func castActivityValidation(stub shim.ChaincodeStubInterface, callerID string, txTime string, activityID string, trackName string, status string, remarks string, evidence []string, proofVersionArg string) pb.Response {
+	// check the activity
+	activity, err := getActivity(stub, activityID)
+	if err != nil {
+		fmt.Println("ActivityID is not present " + activityID)
 		return shim.Error(err.Error())
 	}
 
@@ -869,49 +1204,74 @@ func updateActivityValidation(stub shim.ChaincodeStubInterface, args []string) p
 		return shim.Error(err.Error())
 	}
 
-	//update activity
-	var paramStatus = args[1]
-	if paramStatus == "Validation Successful" {
-		fmt.Println(project.Flag)
-		fmt.Println(activity.Status)
-		fmt.Println(paramStatus)
-		fmt.Println(activity.SecondaryValidation == true)
-		fmt.Println(activity.Status == "Partial Validation Successful")
-		if activity.SecondaryValidation == true {
-			if activity.Status == "Partial Validation Successful" {
-				project.Flag = "Activity " + activity.ActivityName + " has been validated by " + string(certname)
-				activity.Status = "Validation Successful"
-			} else {
-				project.Flag = "Partial Validation of Activity " + activity.ActivityName + " has been done by " + string(certname)
-				activity.Status = "Partial Validation Successful"
-			}
-			fmt.Println(project.Flag)
-			fmt.Println(activity.Status)
+	//votes are cast on the latest proof, a client naming an older one saw a stale activity
+	proofVersion := activity.ProofVersion
+	if len(proofVersionArg) > 0 && proofVersionArg != strconv.Itoa(activity.ProofVersion) {
+		return shim.Error("Proof version " + proofVersionArg + " is not the latest proof of activity " + activity.ActivityID + ", expecting " + strconv.Itoa(activity.ProofVersion))
+	}
+	approve := status == "Validation Successful" || status == "Approved"
+	if approve && proofVersion == 0 {
+		return shim.Error("Activity " + activity.ActivityID + " has no proof yet and can't be approved")
+	}
+	err = seedValidatorPanel(&activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 
-		} else {
-			project.Flag = "Activity " + activity.ActivityName + " has been validated by " + string(certname)
-			activity.Status = "Validation Successful"
+	//record the vote of the calling validator on the requested tracks
+	var tracks []*ValidationPanel
+	if len(trackName) > 0 {
+		track, err := validationTrack(&activity, trackName)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		tracks = append(tracks, track)
+	} else {
+		for _, track := range []*ValidationPanel{&activity.TechnicalValidation, &activity.FinancialValidation} {
+			if track.isValidator(callerID) {
+				tracks = append(tracks, track)
+			}
+		}
+		if len(tracks) == 0 {
+			return shim.Error(callerID + " is no validator of activity " + activity.ActivityID)
+		}
+	}
+	for _, track := range tracks {
+		err = checkValidatorRole(stub, track, callerID)
+		if err != nil {
+			return shim.Error(err.Error())
 		}
+		err = track.castVote(callerID, approve, remarks, evidence, proofVersion, txTime)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	//update activity
+	activity.Status = validationStatus(activity)
+	if activity.Status == "Validation Successful" {
+		project.Flag = "Activity " + activity.ActivityName + " has been validated by " + callerID
+	} else if activity.Status == "Validation failed" {
+		project.Flag = "Validation of Activity " + activity.ActivityName + " has been rejected by " + callerID
 	} else {
-		project.Flag = "Validation of Activity " + activity.ActivityName + " has been rejected by " + string(certname)
-		activity.Status = "Validation failed"
+		project.Flag = "Partial Validation of Activity " + activity.ActivityName + " has been done by " + callerID
 	}
+	activity.PartialValidation = activity.Status != "Validation Successful" && activity.Status != "Validation failed"
+	activity.Validation = activity.Status == "Validation Successful"
 	log.Println("update activity status object is creataed ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
-	fmt.Println("errz")
-	fmt.Println(errz)
 	if errz != nil {
 		log.Println("Could not update the status and flag of project")
 		return shim.Error(errz.Error())
 	}
 
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
-	fmt.Println("erra")
-	fmt.Println(erra)
 	if erra != nil {
 		fmt.Println("Could not update activity")
 		return shim.Error(erra.Error())
@@ -927,19 +1287,30 @@ func updateActivityStatus(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	var err error
 	log.Println("starting - update activity status")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 7 {
 		return shim.Error("Incorrect number of arguments. Expecting 7")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[1])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -966,9 +1337,15 @@ func updateActivityStatus(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	//update activity
 	activity.Status = args[1]
-	activity.IsApproved = parseBool(args[2])
+	//args[2] isApproved is ignored, activities are approved with their project, see approval.go
 	activity.Remarks = args[3]
 	// upate milestone
 	milestone.Status = args[4]
@@ -980,6 +1357,7 @@ func updateActivityStatus(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	log.Println("update milestone status object is creataed ", project)
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -989,6 +1367,7 @@ func updateActivityStatus(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errm != nil {
@@ -996,6 +1375,7 @@ func updateActivityStatus(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		return shim.Error(errm.Error())
 	}
 
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -1013,19 +1393,19 @@ func deleteActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 	var err error
 	log.Println("starting - delete activity")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
 	if len(args) != 1 {
 		return shim.Error("Incorrect number of arguments. Expecting 1")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1037,6 +1417,30 @@ func deleteActivity(stub shim.ChaincodeStubInterface, args []string) pb.Response
 		return shim.Error(err.Error())
 	}
 
+	// get the milestone
+	milestone, err := getMilestone(stub, activity.MilestoneID)
+	if err != nil {
+		fmt.Println("Milestone is not present " + activity.MilestoneID)
+		return shim.Error(err.Error())
+	}
+
+	// get the project
+	project, err := getProject(stub, activity.ProjectID)
+	if err != nil {
+		fmt.Println("Project is missing " + activity.ProjectID)
+		return shim.Error(err.Error())
+	}
+
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkStructureLocked(isMilestoneLocked(project, milestone) || isActivityLocked(project, activity), "Activity "+activity.ActivityID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	log.Println("delete activity ", activity)
 
 	err = stub.DelState(args[0]) //remove the key from chaincode state
@@ -1056,19 +1460,24 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - fund project")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	log.Println(certname)
+	log.Println(callerID)
 
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 && len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 3 or 5")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1079,22 +1488,65 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		fmt.Println("Project is missing ", args[0])
 		return shim.Error(err.Error())
 	}
-	var donationAmt = parseFloat(args[1])
-	project.FundRaised += parseFloat(args[1])
-	donationAmt += project.FundNotAllocated
+	//where the donation was made, optional
+	if len(args) == 5 {
+		project.TransactionLoc, err = parseLocation(args[3], args[4])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	var amount = parseFloat(args[1])
+	if amount <= 0 {
+		return shim.Error("Donation amount must be a positive number")
+	}
 	project.Flag = args[2]
+	err = allocateDonation(stub, &project, amount, callerID, callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//record the donation
+	donation, err := newDonation(stub, project, amount, callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addDonationToDonor(stub, callerID, donation.DonationID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	log.Println("project object after donation ", project)
+
+	//store project
+	project.setUpdated(callerID, txTime)
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	errz := stub.PutState(project.ProjectID, projectAsBytes)
+	if errz != nil {
+		log.Println("could not fund project")
+		return shim.Error(errz.Error())
+	}
+
+	log.Println("- end - fund project")
+
+	return shim.Success(nil)
+}
+
+//allocateDonation books a donation on the project, automated projects spread it over their activities chronologically
+func allocateDonation(stub shim.ChaincodeStubInterface, project *Project, amount float64, donorLabel string, actor string, txTime string) error {
+	var donationAmt = amount
+	project.FundRaised += amount
+	donationAmt += project.FundNotAllocated
 	if project.FundAllocationType == "2" { // auto fund allocate
 		//get all activities whose activity budget is >= donation amount (sort by date= chronologically)
-		queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Activity\",\"projectId\":\"%s\"}}", args[0])
+		queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Activity\",\"projectId\":\"%s\"}}", project.ProjectID)
 		act, err := myfunction(stub, queryString)
 		if err != nil {
-			return shim.Error(err.Error())
+			return err
 		}
 		var activities []Activity
 		json.Unmarshal([]byte(act), &activities)
 		fmt.Println("Activities are: ", activities)
 		sort.SliceStable(activities, func(i, j int) bool {
-			return activities[i].StartDate < activities[j].StartDate
+			return startsBefore(activities[i].StartDate, activities[j].StartDate)
 		})
 		fmt.Println("Kuldeep sorted activites=> ")
 		actBalAmt := donationAmt
@@ -1106,7 +1558,7 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 					milestone, err := getMilestone(stub, activities[i].MilestoneID)
 					if err != nil {
 						fmt.Println("Milestone is not present " + activities[i].MilestoneID)
-						return shim.Error(err.Error())
+						return err
 					}
 					activities[i].FundAllocated += actFundRem
 					activities[i].Status = "Fund Allocated"
@@ -1115,13 +1567,14 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 					milestone.Status = "Fund Allocated"
 					// project.Status = "Fund Allocated"
 					project.FundNotAllocated = project.FundNotAllocated - project.FundNotAllocated
+					milestone.setUpdated(actor, txTime)
 					milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 					fr := fmt.Sprint(actFundRem)
-					prjDonations = project.ProjectID + "-" + activities[i].MilestoneID + "-" + activities[i].ActivityID + "-" + string(certname) + "-" + fr
+					prjDonations = project.ProjectID + "-" + activities[i].MilestoneID + "-" + activities[i].ActivityID + "-" + donorLabel + "-" + fr
 					project.Donations = append(project.Donations, prjDonations)
 					errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 					if errm != nil {
-						return shim.Error(errm.Error())
+						return errm
 					}
 				}
 			} else {
@@ -1139,6 +1592,7 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 			}
 
 			//update actvity
+			activities[i].setUpdated(actor, txTime)
 			actAsBytes, _ := json.Marshal(activities[i])                  //convert to array of bytes
 			errAct := stub.PutState(activities[i].ActivityID, actAsBytes) //rewrite the project with id as key
 			if errAct != nil {
@@ -1146,38 +1600,37 @@ func fundProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 			}
 		}
 	} else {
-		project.FundNotAllocated += parseFloat(args[1])
-	}
-	log.Println("project object after donation ", project)
-
-	//store project
-	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
-	errz := stub.PutState(project.ProjectID, projectAsBytes)
-	if errz != nil {
-		log.Println("could not fund project")
-		return shim.Error(errz.Error())
+		project.FundNotAllocated += amount
 	}
-
-	log.Println("- end - fund project")
-
-	return shim.Success(nil)
+	return nil
 }
 
 func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 7 {
 		return shim.Error("Incorrect number of arguments. Expecting 7")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[2])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1203,6 +1656,12 @@ func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		fmt.Println("Project is missing " + activity.ProjectID)
 		return shim.Error(err.Error())
 	}
+
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	var fund = parseFloat(args[1])
 	activity.FundAllocated = parseFloat(args[1])
 	activity.Status = args[2]
@@ -1215,11 +1674,12 @@ func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	project.Status = args[4]
 	project.FundNotAllocated = parseFloat(args[5])
 	fr := fmt.Sprint(fund)
-	prjDonations = project.ProjectID + "-" + activity.MilestoneID + "-" + activity.ActivityID + "-" + string(certname) + "-" + fr
+	prjDonations = project.ProjectID + "-" + activity.MilestoneID + "-" + activity.ActivityID + "-" + callerID + "-" + fr
 	project.Donations = append(project.Donations, prjDonations)
 	project.Flag = args[6]
 
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -1229,6 +1689,7 @@ func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 
 	//update milestone
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -1237,6 +1698,7 @@ func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 
 	//update activity
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -1251,19 +1713,30 @@ func fundAllocateManually(stub shim.ChaincodeStubInterface, args []string) pb.Re
 
 func balancedfundAllocate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 6 {
 		return shim.Error("Incorrect number of arguments. Expecting 6")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[2])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1289,6 +1762,12 @@ func balancedfundAllocate(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	funds := parseFloat(args[1])
 	if funds == activity.ActivityBudget {
 		activity.FundAllocated += funds
@@ -1313,6 +1792,7 @@ func balancedfundAllocate(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		project.FundNotAllocated += parseFloat(args[1])
 	}
 	//update project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errp := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -1322,6 +1802,7 @@ func balancedfundAllocate(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 
 	//update milestone
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errz := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errz != nil {
@@ -1330,6 +1811,7 @@ func balancedfundAllocate(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 
 	//update activity
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -1354,19 +1836,30 @@ func fundReq(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - fund request")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 6 {
 		return shim.Error("Incorrect number of arguments. Expecting 6")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[1])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1393,6 +1886,12 @@ func fundReq(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	//update activity
 	activity.Status = args[1]
 	activity.FundRequested = parseFloat(args[2])
@@ -1406,6 +1905,7 @@ func fundReq(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	project.Flag = args[5]
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -1415,6 +1915,7 @@ func fundReq(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errm != nil {
@@ -1422,6 +1923,7 @@ func fundReq(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(errm.Error())
 	}
 
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -1439,19 +1941,30 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("starting - fund release")
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	log.Println(certname)
 
 	if len(args) != 6 {
 		return shim.Error("Incorrect number of arguments. Expecting 6")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[1])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -1478,6 +1991,17 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//funds are only released once the financial validation has passed
+	if activity.FinancialValidation.Outcome != "Passed" {
+		return shim.Error("Financial validation of activity " + activity.ActivityID + " has not passed, funds can not be released")
+	}
+
 	//update activity
 	activity.Status = args[1]
 	activity.FundReleased = parseFloat(args[2])
@@ -1491,6 +2015,7 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	project.Flag = args[5]
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -1500,6 +2025,7 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errm != nil {
@@ -1507,6 +2033,7 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(errm.Error())
 	}
 
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
@@ -1520,7 +2047,7 @@ func fundRelease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 }
 
 //==============PROOF RELATED FUNCTION'S START HERE ===================================================
-//submitProof
+//submitProof - args[2] is a JSON array of proof documents or a single legacy hash
 func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 
@@ -1529,13 +2056,35 @@ func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 6 {
 		return shim.Error("Incorrect number of arguments. Expecting 6")
 	}
-	certname, err := get_cert(stub)
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
 
-	fmt.Println("certname ", string(certname))
+	//validation statuses are only set by the votes of the validator panels
+	err = checkActivityStatus(args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	documents, err := parseProofDocuments(args[2])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("caller ", callerID)
 
 	// check the activity
 	activity, err := getActivity(stub, args[0])
@@ -1559,9 +2108,34 @@ func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(err.Error())
 	}
 
+	//only members of the project may change it
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//proofs have to come from the project site when a geofence is set
+	err = checkGeofence(project, documents)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for i := range documents {
+		if documents[i].Geotag != nil {
+			activity.TransactionLoc = *documents[i].Geotag
+			project.TransactionLoc = *documents[i].Geotag
+			break
+		}
+	}
+
+	//store the proof as a new version, previous versions stay untouched
+	proof, err := newProof(stub, &activity, documents, callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	log.Println("stored proof ", proof.ProofID)
+
 	//update activity
 	activity.Status = args[1]
-	activity.ProofHash = args[2]
 
 	// upate milestone
 	milestone.Status = args[3]
@@ -1571,6 +2145,7 @@ func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	project.Flag = args[5]
 
 	//store project
+	project.setUpdated(callerID, txTime)
 	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
 	errz := stub.PutState(project.ProjectID, projectAsBytes)
 
@@ -1580,6 +2155,7 @@ func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//store project
+	milestone.setUpdated(callerID, txTime)
 	milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
 	errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
 	if errm != nil {
@@ -1587,6 +2163,7 @@ func submitProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		return shim.Error(errm.Error())
 	}
 
+	activity.setUpdated(callerID, txTime)
 	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
 	erra := stub.PutState(activity.ActivityID, activityAsBytes)
 	if erra != nil {
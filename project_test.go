@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+)
+
+//projectArgs are the arguments of addProject and updateProject for a project with the given id, owner and budget
+func projectArgs(projectID string, owner string, budget string) []string {
+	return []string{projectID, owner, "NGO", "Wells", "1000", "Water", "2026-01-01", "2026-12-31", "Two wells", "USD",
+		"0", "0", budget, "[]", "2", "false", "Open", "new", "[]", "-1.28", "36.82", "Kenya", "0", "[]"}
+}
+
+func milestoneArgs(projectID string, milestoneID string) []string {
+	return []string{projectID, milestoneID, "Drilling", "2026-02-01", "2026-05-31", "Drill", "Open", "true", "Open", "new"}
+}
+
+func activityArgs(projectID string, milestoneID string, activityID string, validator string, budget string) []string {
+	return []string{projectID, milestoneID, activityID, "Survey", "2026-02-01", "2026-02-28", budget, "Survey the site",
+		"false", "none", "true", validator, "Open", "site surveyed", "costs receipted", "Open", "Open", "new"}
+}
+
+//addTestProject stores a project of the owner with one milestone M1 and one activity A1
+func addTestProject(t *testing.T, n *testNetwork, projectID string) {
+	expectOK(t, n.stub.invoke(n.owner, "addProject", projectArgs(projectID, n.stub.callerID(t, n.owner), "1000")...))
+	expectOK(t, n.stub.invoke(n.owner, "addMilestone", milestoneArgs(projectID, projectID+"-M1")...))
+	expectOK(t, n.stub.invoke(n.owner, "addActivity", activityArgs(projectID, projectID+"-M1", projectID+"-A1", n.stub.callerID(t, n.validator), "400")...))
+}
+
+func TestProjectWritesNeedMembership(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+
+	expectError(t, n.stub.invoke(n.outsider, "updateProject", projectArgs("PRJ-1", n.stub.callerID(t, n.outsider), "1000")...))
+	expectError(t, n.stub.invoke(n.outsider, "addMilestone", milestoneArgs("PRJ-1", "PRJ-1-M2")...))
+	expectError(t, n.stub.invoke(n.outsider, "addActivity", activityArgs("PRJ-1", "PRJ-1-M1", "PRJ-1-A2", n.stub.callerID(t, n.validator), "100")...))
+	expectError(t, n.stub.invoke(n.outsider, "deleteActivity", "PRJ-1-A1"))
+	expectError(t, n.stub.invoke(n.outsider, "deleteProject", "PRJ-1"))
+	if _, err := getProject(n.stub, "PRJ-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFundsAndApprovalsAreNotTakenFromArguments(t *testing.T) {
+	n := newTestNetwork(t)
+	args := projectArgs("PRJ-1", n.stub.callerID(t, n.owner), "1000")
+	args[10], args[11], args[22] = "5000", "4000", "1000"
+	expectOK(t, n.stub.invoke(n.owner, "addProject", args...))
+	expectOK(t, n.stub.invoke(n.owner, "addMilestone", milestoneArgs("PRJ-1", "PRJ-1-M1")...))
+	expectOK(t, n.stub.invoke(n.owner, "addActivity", activityArgs("PRJ-1", "PRJ-1-M1", "PRJ-1-A1", n.stub.callerID(t, n.validator), "400")...))
+
+	project, err := getProject(n.stub, "PRJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.FundRaised != 0 || project.FundAllocated != 0 || project.FundNotAllocated != 0 || isProjectLocked(project) {
+		t.Fatalf("funds were taken from the arguments: %+v", project)
+	}
+	milestone, _ := getMilestone(n.stub, "PRJ-1-M1")
+	activity, _ := getActivity(n.stub, "PRJ-1-A1")
+	if milestone.IsApproved || activity.IsApproved {
+		t.Fatal("milestone or activity was approved by its arguments")
+	}
+
+	expectOK(t, n.stub.invoke(n.owner, "updateActivityStatus", "PRJ-1-A1", "Open", "true", "started", "Open", "Open", "started"))
+	activity, _ = getActivity(n.stub, "PRJ-1-A1")
+	if activity.IsApproved {
+		t.Fatal("activity was approved by updateActivityStatus")
+	}
+}
+
+func TestFundedProjectIsLocked(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+	owner := n.stub.callerID(t, n.owner)
+
+	expectOK(t, n.stub.invoke(n.outsider, "fundProject", "PRJ-1", "100", "donated"))
+	project, err := getProject(n.stub, "PRJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isProjectLocked(project) {
+		t.Fatal("funded project is not locked")
+	}
+
+	//budget, owner, funds and structure of a funded project stay as they are
+	expectError(t, n.stub.invoke(n.owner, "updateProject", projectArgs("PRJ-1", owner, "2000")...))
+	expectError(t, n.stub.invoke(n.owner, "updateProject", projectArgs("PRJ-1", n.stub.callerID(t, n.outsider), "1000")...))
+	expectOK(t, n.stub.invoke(n.owner, "updateProject", projectArgs("PRJ-1", owner, "1000")...))
+	project, _ = getProject(n.stub, "PRJ-1")
+	if project.FundRaised != 100 {
+		t.Fatalf("updateProject reset the raised funds to %v", project.FundRaised)
+	}
+	expectError(t, n.stub.invoke(n.owner, "addMilestone", milestoneArgs("PRJ-1", "PRJ-1-M2")...))
+	expectError(t, n.stub.invoke(n.owner, "addActivity", activityArgs("PRJ-1", "PRJ-1-M1", "PRJ-1-A2", n.stub.callerID(t, n.validator), "100")...))
+	expectError(t, n.stub.invoke(n.owner, "deleteActivity", "PRJ-1-A1"))
+	expectError(t, n.stub.invoke(n.owner, "deleteMilestone", "PRJ-1-M1"))
+	expectError(t, n.stub.invoke(n.owner, "deleteProject", "PRJ-1"))
+	if _, err := getProject(n.stub, "PRJ-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnlockedProjectCanBeDeleted(t *testing.T) {
+	n := newTestNetwork(t)
+	addTestProject(t, n, "PRJ-1")
+
+	expectOK(t, n.stub.invoke(n.owner, "deleteProject", "PRJ-1"))
+	if _, err := getProject(n.stub, "PRJ-1"); err == nil {
+		t.Fatal("project was not deleted")
+	}
+}
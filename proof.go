@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== PROOF OF WORK RELATED FUNCTION'S START HERE ===============================================================
+
+//proofID names one proof version of an activity, e.g. ACT-1-PROOF-2
+func proofID(activityID string, version int) string {
+	return fmt.Sprintf("%s-PROOF-%d", activityID, version)
+}
+
+//proofKey is the ledger key of one proof version, kept out of the keys clients can choose
+func proofKey(activityID string, version int) string {
+	return reservedKey("Proof", activityID, strconv.Itoa(version))
+}
+
+// ============================================================================================================================
+// Get Proof - get one proof version from ledger
+// ============================================================================================================================
+func getProof(stub shim.ChaincodeStubInterface, activityID string, version int) (Proof, error) {
+	var proof Proof
+	id := proofID(activityID, version)
+	key := proofKey(activityID, version)
+	proofAsBytes, err := stub.GetState(key) //getState retreives a key/value from the ledger
+	if err != nil {                         //this seems to always succeed, even if key didn't exist
+		return proof, errors.New("Failed to get proof by id - " + id)
+	}
+	unmarshalDocument(proofAsBytes, &proof) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(proof.ProofID) == 0 { //test if proof is actually here or just nil
+		return proof, errors.New("proof does not exist - " + id)
+	}
+
+	return proof, nil
+}
+
+// ============================================================================================================================
+// Get Proofs By Activity - every proof version submitted for an activity, oldest first
+// ============================================================================================================================
+func getProofsByActivity(stub shim.ChaincodeStubInterface, activity Activity) ([]Proof, error) {
+	var proofs []Proof
+	for version := 1; version <= activity.ProofVersion; version++ {
+		proof, err := getProof(stub, activity.ActivityID, version)
+		if err != nil {
+			return proofs, err
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, nil
+}
+
+//base58Alphabet is the bitcoin alphabet used by base58btc encoded multihashes
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+//decodeBase58 will decode a base58btc string
+func decodeBase58(str string) ([]byte, error) {
+	value := big.NewInt(0)
+	radix := big.NewInt(58)
+	for _, r := range str {
+		digit := strings.IndexRune(base58Alphabet, r)
+		if digit < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+	decoded := value.Bytes()
+	for i := 0; i < len(str) && str[i] == '1'; i++ { //leading ones are leading zero bytes
+		decoded = append([]byte{0}, decoded...)
+	}
+	return decoded, nil
+}
+
+//isMultihash checks the <hash function code><digest length><digest> layout of a multihash
+func isMultihash(decoded []byte) bool {
+	if len(decoded) < 3 || decoded[0] >= 0x80 || decoded[1] >= 0x80 { //single byte varints only
+		return false
+	}
+	return int(decoded[1]) > 0 && len(decoded) == 2+int(decoded[1])
+}
+
+//isHex tells whether the string is plain hexadecimal
+func isHex(str string) bool {
+	_, err := hex.DecodeString(str)
+	return err == nil
+}
+
+//normalizeProofHash checks the hash against its algorithm and returns it in canonical form
+func normalizeProofHash(hash string, algorithm string) (string, error) {
+	if algorithm == "sha256" {
+		if len(hash) != 64 || !isHex(hash) {
+			return hash, errors.New("'" + hash + "' is not a SHA-256 hex digest")
+		}
+		return strings.ToLower(hash), nil
+	} else if algorithm == "multihash" {
+		if isHex(hash) {
+			decoded, _ := hex.DecodeString(hash)
+			if isMultihash(decoded) {
+				return strings.ToLower(hash), nil
+			}
+		} else if decoded, err := decodeBase58(hash); err == nil && isMultihash(decoded) {
+			return hash, nil
+		}
+		return hash, errors.New("'" + hash + "' is not a hex or base58 multihash")
+	}
+	return hash, errors.New("Unknown hash algorithm '" + algorithm + "'. Expecting sha256 or multihash")
+}
+
+//detectHashAlgorithm guesses the algorithm of a bare hash, SHA-256 hex or multihash
+func detectHashAlgorithm(hash string) string {
+	if len(hash) == 64 && isHex(hash) {
+		return "sha256"
+	}
+	return "multihash"
+}
+
+//parseProofDocuments reads the documents of a submission, a plain string is taken as a single bare hash
+func parseProofDocuments(str string) ([]ProofDocument, error) {
+	var documents []ProofDocument
+	if !strings.HasPrefix(strings.TrimSpace(str), "[") {
+		var document ProofDocument
+		document.HashAlgorithm = detectHashAlgorithm(str)
+		hash, err := normalizeProofHash(str, document.HashAlgorithm)
+		if err != nil {
+			return documents, err
+		}
+		document.Hash = hash
+		return append(documents, document), nil
+	}
+
+	err := json.Unmarshal([]byte(str), &documents)
+	if err != nil {
+		return documents, errors.New("Expecting a JSON array of proof documents")
+	}
+	if len(documents) == 0 {
+		return documents, errors.New("A proof needs at least one document")
+	}
+	for i := range documents {
+		if len(documents[i].HashAlgorithm) == 0 {
+			documents[i].HashAlgorithm = detectHashAlgorithm(documents[i].Hash)
+		}
+		hash, err := normalizeProofHash(documents[i].Hash, documents[i].HashAlgorithm)
+		if err != nil {
+			return documents, errors.New("Proof document " + fmt.Sprint(i) + ": " + err.Error())
+		}
+		documents[i].Hash = hash
+		if documents[i].Geotag != nil {
+			err = validateLocation(*documents[i].Geotag)
+			if err != nil {
+				return documents, errors.New("Proof document " + fmt.Sprint(i) + " geotag: " + err.Error())
+			}
+		}
+	}
+	return documents, nil
+}
+
+// ============================================================================================================================
+// newProof - store the next immutable proof version of an activity
+// ============================================================================================================================
+func newProof(stub shim.ChaincodeStubInterface, activity *Activity, documents []ProofDocument, submitter string, txTime string) (Proof, error) {
+	var proof Proof
+	proof.ObjectType = "Proof"
+	proof.SchemaVersion = currentSchemaVersion
+	proof.Version = activity.ProofVersion + 1
+	proof.ProofID = proofID(activity.ActivityID, proof.Version)
+	proof.ActivityID = activity.ActivityID
+	proof.MilestoneID = activity.MilestoneID
+	proof.ProjectID = activity.ProjectID
+	proof.Documents = documents
+	proof.SubmittedBy = submitter
+	proof.SubmittedAt = txTime
+	proof.setCreated(submitter, txTime)
+
+	//earlier proofs are never overwritten
+	_, err := getProof(stub, proof.ActivityID, proof.Version)
+	if err == nil {
+		return proof, errors.New("Proof is already present " + proof.ProofID)
+	}
+
+	proofAsBytes, _ := json.Marshal(proof) //convert to array of bytes
+	err = stub.PutState(proofKey(proof.ActivityID, proof.Version), proofAsBytes)
+	if err != nil {
+		return proof, err
+	}
+
+	activity.ProofVersion = proof.Version
+	activity.ProofHash = documents[0].Hash
+	return proof, nil
+}
+
+//getProofs - every proof version of an activity
+func getProofs(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get proofs")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// check the activity
+	activity, err := getActivity(stub, args[0])
+	if err != nil {
+		fmt.Println("ActivityID is not present " + args[0])
+		return shim.Error(err.Error())
+	}
+	err = checkCanViewProject(stub, activity.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	proofs, err := getProofsByActivity(stub, activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	proofsAsBytes, _ := json.Marshal(proofs) //convert to array of bytes
+
+	log.Println("- end - get proofs")
+	return shim.Success(proofsAsBytes)
+}
+
+// ============================================================================================================================
+// verifyProof() - check a document held off-chain against the proofs on the ledger
+//
+// Inputs - Array of strings
+//       0     ,       1
+//  activityId , candidate hash (SHA-256 hex or multihash)
+// ============================================================================================================================
+func verifyProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - verify proof")
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	candidate, err := normalizeProofHash(args[1], detectHashAlgorithm(args[1]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// check the activity
+	activity, err := getActivity(stub, args[0])
+	if err != nil {
+		fmt.Println("ActivityID is not present " + args[0])
+		return shim.Error(err.Error())
+	}
+	err = checkCanViewProject(stub, activity.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	proofs, err := getProofsByActivity(stub, activity)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//the earliest version holding the hash proves since when the document exists
+	var verification ProofVerification
+	verification.ActivityID = activity.ActivityID
+	verification.CandidateHash = candidate
+	for _, proof := range proofs {
+		for i, document := range proof.Documents {
+			if document.Hash == candidate && !verification.Matched {
+				verification.Matched = true
+				verification.ProofID = proof.ProofID
+				verification.Version = proof.Version
+				verification.DocumentIndex = i
+				verification.HashAlgorithm = document.HashAlgorithm
+				verification.SubmittedBy = proof.SubmittedBy
+				verification.SubmittedAt = proof.SubmittedAt
+			}
+		}
+	}
+
+	verificationAsBytes, _ := json.Marshal(verification) //convert to array of bytes
+
+	log.Println("- end - verify proof")
+	return shim.Success(verificationAsBytes)
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -24,7 +26,7 @@ func getProject(stub shim.ChaincodeStubInterface, id string) (Project, error) {
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return project, errors.New("Failed to get project by id - " + id)
 	}
-	json.Unmarshal(userAsBytes, &project) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &project) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(project.ProjectID) == 0 { //test if user is actually here or just nil
 		return project, errors.New("project does not exist - " + id + ", '" + project.ProjectID + "' '")
@@ -42,7 +44,7 @@ func getMilestone(stub shim.ChaincodeStubInterface, id string) (Milestone, error
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return milestone, errors.New("Failed to get milestone by id - " + id)
 	}
-	json.Unmarshal(userAsBytes, &milestone) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &milestone) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(milestone.MilestoneID) == 0 { //test if user is actually here or just nil
 		return milestone, errors.New("milestone does not exist - " + id + ", '" + milestone.MilestoneID + "' '")
@@ -60,7 +62,7 @@ func getActivity(stub shim.ChaincodeStubInterface, id string) (Activity, error)
 	if err != nil {                       //this seems to always succeed, even if key didn't exist
 		return activity, errors.New("Failed to get activity by id - " + id)
 	}
-	json.Unmarshal(userAsBytes, &activity) //un stringify it aka JSON.parse()
+	unmarshalDocument(userAsBytes, &activity) //un stringify it aka JSON.parse(), migrated to the current schema
 
 	if len(activity.ActivityID) == 0 { //test if user is actually here or just nil
 		return activity, errors.New("activity does not exist - " + id + ", '" + activity.ActivityID + "' '")
@@ -75,6 +77,13 @@ func getActivity(stub shim.ChaincodeStubInterface, id string) (Activity, error)
 // 	return shim.Success(nil)
 // }
 
+// ============================================================================================================================
+// getHistory() - every version of a document the caller may read, the document types are the ones of read()
+//
+// Inputs - Array of strings
+//      0     ,    1
+//   docType  ,   key
+// ============================================================================================================================
 func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	/*type AuditHistory struct {
 		TxId  string `json:"txId"`
@@ -84,16 +93,43 @@ func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var project Asset
 	*/
 
-	projectId := args[0]
-	fmt.Printf("- start getHistoryForProject: %s\n", projectId)
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err := sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if !readableTypes[args[0]] {
+		return shim.Error("Unknown document type '" + args[0] + "'")
+	}
+	return keyHistory(stub, documentKey(args[0], args[1]), args[0])
+}
+
+//keyHistory lists the versions of the document of that type stored under the key, versions of another type or
+//which the caller may not read are left out together with their deletion
+func keyHistory(stub shim.ChaincodeStubInterface, key string, docType string) pb.Response {
+	fmt.Printf("- start getHistoryForProject: %s\n", key)
 
 	// Get History
-	resultsIterator, err := stub.GetHistoryForKey(projectId)
+	resultsIterator, err := stub.GetHistoryForKey(key)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 	defer resultsIterator.Close()
 
+	visibility, err := newRecordVisibility(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	var doc struct {
+		ObjectType string `json:"docType"`
+	}
+	previousVisible := false
+
 	// buffer is a JSON array containing historic values for the marble
 	var buffer bytes.Buffer
 	buffer.WriteString("[")
@@ -105,6 +141,19 @@ func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		if response.IsDelete {
+			if !previousVisible {
+				continue
+			}
+			previousVisible = false
+		} else {
+			doc.ObjectType = ""
+			json.Unmarshal(response.Value, &doc)
+			previousVisible = doc.ObjectType == docType && visibility.canViewRecord(response.Value)
+			if !previousVisible {
+				continue
+			}
+		}
 
 		// Add a comma before array members, suppress it for the first array member
 		if bArrayMemberAlreadyWritten == true {
@@ -122,7 +171,11 @@ func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 		if response.IsDelete {
 			buffer.WriteString("null")
 		} else {
-			buffer.WriteString(string(response.Value))
+			value, _, err := migrateDocument(response.Value)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			buffer.WriteString(string(value))
 		}
 
 		buffer.WriteString(", \"Timestamp\":")
@@ -147,17 +200,246 @@ func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	return shim.Success(buffer.Bytes())
 }
 
-//query
+//query - rich query, one page at a time
+//
+// Inputs - queryString without "fields", page size (optional, at most maxPageSize of the configuration), bookmark (optional)
 func query(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	log.Println("***********Entering getQuery***********")
 
 	log.Println(args)
 
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting at least 1")
+	}
+
 	queryString := args[0]
 
-	queryResults, err := getQueryResultForQueryStringCouch(stub, queryString)
+	//the results are filtered by docType and projectId, a projection could drop both
+	var parsed map[string]json.RawMessage
+	err := json.Unmarshal([]byte(queryString), &parsed)
+	if err != nil {
+		return shim.Error("Expecting a JSON query string")
+	}
+	if _, ok := parsed["fields"]; ok {
+		return shim.Error("Queries can not select fields, the full documents are returned")
+	}
+
+	queryResults, err := getQueryPage(stub, queryString, args[1:])
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 	return shim.Success(queryResults)
 }
+
+// ============================================================================================================================
+// Get Milestones By Project - get all milestones of a project from ledger
+// ============================================================================================================================
+func getMilestonesByProject(stub shim.ChaincodeStubInterface, projectID string) ([]Milestone, error) {
+	var milestones []Milestone
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Milestone\",\"projectId\":\"%s\"}}", projectID)
+	milestonesAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return milestones, errors.New("Failed to get milestones of project - " + projectID)
+	}
+	json.Unmarshal(milestonesAsBytes, &milestones) //un stringify it aka JSON.parse()
+
+	return milestones, nil
+}
+
+// ============================================================================================================================
+// Get Activities By Project - get all activities of a project from ledger
+// ============================================================================================================================
+func getActivitiesByProject(stub shim.ChaincodeStubInterface, projectID string) ([]Activity, error) {
+	var activities []Activity
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Activity\",\"projectId\":\"%s\"}}", projectID)
+	activitiesAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return activities, errors.New("Failed to get activities of project - " + projectID)
+	}
+	json.Unmarshal(activitiesAsBytes, &activities) //un stringify it aka JSON.parse()
+
+	return activities, nil
+}
+
+// ============================================================================================================================
+// Get Activities By Milestone - get all activities of a milestone from ledger
+// ============================================================================================================================
+func getActivitiesByMilestone(stub shim.ChaincodeStubInterface, milestoneID string) ([]Activity, error) {
+	var activities []Activity
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Activity\",\"milestoneId\":\"%s\"}}", milestoneID)
+	activitiesAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return activities, errors.New("Failed to get activities of milestone - " + milestoneID)
+	}
+	json.Unmarshal(activitiesAsBytes, &activities) //un stringify it aka JSON.parse()
+
+	return activities, nil
+}
+
+//activityPercentComplete - an activity is complete once it has been validated
+func activityPercentComplete(activity Activity) float64 {
+	if activity.Status == "Validation Successful" {
+		return 100
+	}
+	return 0
+}
+
+//weightedPercent - budget weighted percent complete, falls back to a plain average when nothing is budgeted
+func weightedPercent(budgets []float64, percents []float64) float64 {
+	if len(percents) == 0 {
+		return 0
+	}
+	var totalBudget, weighted, plain float64
+	for i := range percents {
+		totalBudget += budgets[i]
+		weighted += budgets[i] * percents[i]
+		plain += percents[i]
+	}
+	if totalBudget > 0 {
+		return weighted / totalBudget
+	}
+	return plain / float64(len(percents))
+}
+
+// ============================================================================================================================
+// getProjectTree() - project, its milestones and each milestone's activities in one call
+//
+// The totals add up the activities below a node, the project totals also carry the raised and not allocated funds.
+//
+// Inputs - Array of strings
+//       0     ,                   1 (optional)
+//   projectId , fields to exclude e.g. ["activities","description","donations","beneficiaries","criteria","remarks"]
+// ============================================================================================================================
+func getProjectTree(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get project tree")
+
+	if len(args) != 1 && len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 1 or 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	exclude := map[string]bool{}
+	if len(args) == 2 {
+		var excludeList []string
+		err = json.Unmarshal([]byte(args[1]), &excludeList)
+		if err != nil {
+			return shim.Error("Expecting a JSON array of fields to exclude")
+		}
+		for i := range excludeList {
+			exclude[strings.ToLower(excludeList[i])] = true
+		}
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+	err = checkCanView(stub, project)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	milestones, err := getMilestonesByProject(stub, project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	sort.SliceStable(milestones, func(i, j int) bool {
+		return startsBefore(milestones[i].StartDate, milestones[j].StartDate)
+	})
+
+	activitiesByMilestone := map[string][]Activity{}
+	activities, err := getActivitiesByProject(stub, project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	sort.SliceStable(activities, func(i, j int) bool {
+		return startsBefore(activities[i].StartDate, activities[j].StartDate)
+	})
+	for i := range activities {
+		activitiesByMilestone[activities[i].MilestoneID] = append(activitiesByMilestone[activities[i].MilestoneID], activities[i])
+	}
+
+	var tree ProjectTree
+	var milBudgets, milPercents []float64
+	for i := range milestones {
+		var milTree MilestoneTree
+		var actBudgets, actPercents []float64
+		for _, activity := range activitiesByMilestone[milestones[i].MilestoneID] {
+			var node ActivityNode
+			node.Totals.Budget = activity.ActivityBudget
+			node.Totals.Allocated = activity.FundAllocated
+			node.Totals.Requested = activity.FundRequested
+			node.Totals.Released = activity.FundReleased
+			node.Totals.Unallocated = activity.ActivityBudget - activity.FundAllocated
+			node.PercentComplete = activityPercentComplete(activity)
+
+			milTree.Totals.Budget += node.Totals.Budget
+			milTree.Totals.Allocated += node.Totals.Allocated
+			milTree.Totals.Requested += node.Totals.Requested
+			milTree.Totals.Released += node.Totals.Released
+			milTree.Totals.Unallocated += node.Totals.Unallocated
+			actBudgets = append(actBudgets, node.Totals.Budget)
+			actPercents = append(actPercents, node.PercentComplete)
+
+			if exclude["description"] {
+				activity.Description = ""
+			}
+			if exclude["remarks"] {
+				activity.Remarks = ""
+			}
+			if exclude["criteria"] {
+				activity.TechnicalCriteria = ""
+				activity.FinancialCriteria = ""
+			}
+			node.Activity = activity
+			if !exclude["activities"] {
+				milTree.Activities = append(milTree.Activities, node)
+			}
+		}
+		milTree.PercentComplete = weightedPercent(actBudgets, actPercents)
+
+		tree.Totals.Budget += milTree.Totals.Budget
+		tree.Totals.Allocated += milTree.Totals.Allocated
+		tree.Totals.Requested += milTree.Totals.Requested
+		tree.Totals.Released += milTree.Totals.Released
+		tree.Totals.Unallocated += milTree.Totals.Unallocated
+		milBudgets = append(milBudgets, milTree.Totals.Budget)
+		milPercents = append(milPercents, milTree.PercentComplete)
+
+		if exclude["description"] {
+			milestones[i].Description = ""
+		}
+		milTree.Milestone = milestones[i]
+		tree.Milestones = append(tree.Milestones, milTree)
+	}
+	tree.PercentComplete = weightedPercent(milBudgets, milPercents)
+	tree.Totals.Raised = project.FundRaised
+	tree.Totals.NotAllocated = project.FundNotAllocated
+
+	if exclude["description"] {
+		project.Description = ""
+	}
+	if exclude["remarks"] {
+		project.Remarks = ""
+	}
+	if exclude["donations"] {
+		project.Donations = nil
+	}
+	if exclude["beneficiaries"] {
+		project.BeneficiaryIDs = nil
+	}
+	tree.Project = project
+
+	treeAsBytes, _ := json.Marshal(tree) //convert to array of bytes
+
+	log.Println("- end - get project tree")
+	return shim.Success(treeAsBytes)
+}
@@ -21,6 +21,7 @@ under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 
 	//"strings"
@@ -29,17 +30,36 @@ import (
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
+//readableTypes are the documents read() hands out, internal keys like counters and salts are never returned
+var readableTypes = map[string]bool{
+	"PrivateUser":     true,
+	"Donor":           true,
+	"Organization":    true,
+	"Membership":      true,
+	"Project":         true,
+	"Milestone":       true,
+	"Activity":        true,
+	"Beneficiary":     true,
+	"Donation":        true,
+	"Receipt":         true,
+	"ApprovalRequest": true,
+	"ChangeRequest":   true,
+	"ProjectTemplate": true,
+}
+
 // ============================================================================================================================
-// Read - read a generic variable from ledger
+// Read - read a document of a given type from ledger
 //
 // Shows Off GetState() - reading a key/value from the ledger
 //
+// Documents of another type, internal keys and documents of projects the caller may not see are reported as not found.
+//
 // Inputs - Array of strings
-//  0
-//  key
-//  "abc"
+//      0     ,    1
+//   docType  ,   key
+//  "Project" , "PRJ-1"
 //
-// Returns - string
+// Returns - the document
 // ============================================================================================================================
 func read(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var key, jsonResp string
@@ -47,22 +67,44 @@ func read(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	fmt.Println("starting read")
 
 	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting key of the var to query")
+		return shim.Error("Incorrect number of arguments. Expecting document type and key")
 	}
 
 	// input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
+	if !readableTypes[args[0]] {
+		return shim.Error("Unknown document type '" + args[0] + "'")
+	}
+
 	key = args[1]
-	valAsbytes, err := stub.GetState(key) //get the var from ledger
+	valAsbytes, err := stub.GetState(documentKey(args[0], key)) //get the var from ledger
 	if err != nil {
 		jsonResp = "{\"Error\":\"Failed to get state for " + key + "\"}"
 		return shim.Error(jsonResp)
 	}
 
+	var doc struct {
+		ObjectType string `json:"docType"`
+	}
+	json.Unmarshal(valAsbytes, &doc)
+
+	visibility, err := newRecordVisibility(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if doc.ObjectType != args[0] || !visibility.canViewRecord(valAsbytes) {
+		return shim.Error(args[0] + " does not exist - " + key)
+	}
+
+	valAsbytes, _, err = migrateDocument(valAsbytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	fmt.Println("- end read")
 	return shim.Success(valAsbytes) //send it onward
 }
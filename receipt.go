@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== RECEIPT RELATED FUNCTION'S START HERE =======================================================================
+
+// ============================================================================================================================
+// Get Receipt - get the receipt from ledger
+// ============================================================================================================================
+func getReceipt(stub shim.ChaincodeStubInterface, id string) (Receipt, error) {
+	var receipt Receipt
+	receiptAsBytes, err := stub.GetState(documentKey("Receipt", id)) //getState retreives a key/value from the ledger
+	if err != nil {                                                  //this seems to always succeed, even if key didn't exist
+		return receipt, errors.New("Failed to get receipt - " + id)
+	}
+	unmarshalDocument(receiptAsBytes, &receipt) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if len(receipt.ReceiptID) == 0 { //test if receipt is actually here or just nil
+		return receipt, errors.New("receipt does not exist - " + id)
+	}
+
+	return receipt, nil
+}
+
+//donationReceiptKey points from a donation to its receipt, so a donation is never receipted twice
+func donationReceiptKey(donationID string) string {
+	return reservedKey("donationReceipt", donationID)
+}
+
+//nextReceiptNumber hands out the next receipt number of a fiscal year, e.g. RCPT-2026-000042
+func nextReceiptNumber(stub shim.ChaincodeStubInterface, year int) (string, error) {
+	counterKey := reservedKey("receiptCounter", strconv.Itoa(year))
+	counterAsBytes, err := stub.GetState(counterKey)
+	if err != nil {
+		return "", errors.New("Failed to get receipt counter of " + strconv.Itoa(year))
+	}
+	counter := 0
+	if len(counterAsBytes) > 0 {
+		counter, err = strconv.Atoi(string(counterAsBytes))
+		if err != nil {
+			return "", errors.New("Receipt counter of " + strconv.Itoa(year) + " is corrupt")
+		}
+	}
+	counter++
+	err = stub.PutState(counterKey, []byte(strconv.Itoa(counter)))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("RCPT-%d-%06d", year, counter), nil
+}
+
+//getReceiptsByDonor - all receipts of a donor for one fiscal year
+func getReceiptsByDonor(stub shim.ChaincodeStubInterface, donorID string, year int) ([]Receipt, error) {
+	var receipts []Receipt
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Receipt\",\"donorId\":\"%s\",\"fiscalYear\":%d}}", donorID, year)
+	receiptsAsBytes, err := myfunction(stub, queryString)
+	if err != nil {
+		return receipts, errors.New("Failed to get receipts of donor - " + donorID)
+	}
+	json.Unmarshal(receiptsAsBytes, &receipts) //un stringify it aka JSON.parse()
+
+	return receipts, nil
+}
+
+// ============================================================================================================================
+// issueReceipt() - issue the tax receipt of a donation
+//
+// The issuer must be one of the organizations of the donation's project, the caller is that organization or one of its
+// active members. Every donation gets at most one receipt, numbered per fiscal year (calendar year of the donation).
+// Anonymous donations need to be revealed first.
+//
+// Inputs - Array of strings
+//      0     ,        1 (optional)
+//  donationId , issuing orgId, the caller by default
+//
+// Returns - the receipt
+// ============================================================================================================================
+func issueReceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - issue receipt")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 && len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 1 or 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	issuerID := callerID
+	if len(args) == 2 {
+		issuerID = args[1]
+	}
+	issuer, err := getOrg(stub, issuerID)
+	if err != nil {
+		return shim.Error("Only organizations can issue receipts - " + err.Error())
+	}
+	if !isOrgMember(stub, issuer.OrgID, callerID) {
+		return shim.Error(callerID + " is not a member of organization " + issuer.OrgID)
+	}
+
+	donation, err := getDonation(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(donation.DonorID) == 0 {
+		return shim.Error("Donation " + donation.DonationID + " is anonymous, the donor has to reveal it first")
+	}
+
+	existingAsBytes, err := stub.GetState(donationReceiptKey(donation.DonationID))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(existingAsBytes) > 0 {
+		return shim.Error("Donation " + donation.DonationID + " already has receipt " + string(existingAsBytes))
+	}
+
+	donatedAt, err := time.Parse(time.RFC3339, donation.DonatedAt)
+	if err != nil {
+		return shim.Error("Donation " + donation.DonationID + " has no valid date")
+	}
+
+	project, err := getProject(stub, donation.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isListedOrg(project.Organization, issuer.OrgID) {
+		return shim.Error("Organization " + issuer.OrgID + " is not an organization of project " + project.ProjectID)
+	}
+
+	var receipt Receipt
+	receipt.ObjectType = "Receipt"
+	receipt.SchemaVersion = currentSchemaVersion
+	receipt.ReceiptID, err = nextReceiptNumber(stub, donatedAt.Year())
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	receipt.DonationID = donation.DonationID
+	receipt.ProjectID = project.ProjectID
+	receipt.ProjectName = project.ProjectName
+	for _, sdg := range project.SDG {
+		receipt.SDG = append(receipt.SDG, sdg.SDGType)
+	}
+	receipt.IssuerOrgID = issuer.OrgID
+	receipt.IssuerOrgName = issuer.OrgCompany
+	receipt.DonorID = donation.DonorID
+	receipt.Amount = donation.Amount
+	receipt.Currency = donation.Currency
+	receipt.DonationDate = donation.DonatedAt
+	receipt.FiscalYear = donatedAt.Year()
+	receipt.IssuedAt = txTime
+	receipt.setCreated(callerID, txTime)
+
+	//store receipt, it is never updated afterwards
+	receiptAsBytes, _ := json.Marshal(receipt) //convert to array of bytes
+	err = stub.PutState(documentKey("Receipt", receipt.ReceiptID), receiptAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(donationReceiptKey(donation.DonationID), []byte(receipt.ReceiptID))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - issue receipt")
+	return shim.Success(receiptAsBytes)
+}
+
+// ============================================================================================================================
+// getDonorStatement() - annual statement of a donor built from the issued receipts
+//
+// Amounts are summed per currency. A receipt counts in full towards every SDG of its project, so the SDG
+// breakdown can add up to more than the total.
+//
+// Inputs - Array of strings
+//     0    ,    1
+//  donorId , fiscalYear
+// ============================================================================================================================
+func getDonorStatement(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get donor statement")
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerID != args[0] && !isCallerAdmin(stub, callerID) {
+		return shim.Error("A donor statement can only be read by the donor")
+	}
+
+	year, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("Fiscal year must be a number, e.g. 2026")
+	}
+
+	receipts, err := getReceiptsByDonor(stub, args[0], year)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	sort.Slice(receipts, func(i, j int) bool { return receipts[i].ReceiptID < receipts[j].ReceiptID })
+
+	var statement DonorStatement
+	statement.DonorID = args[0]
+	statement.FiscalYear = year
+	statement.Totals = map[string]float64{}
+	statement.BySDG = map[string]map[string]float64{}
+	statement.Receipts = receipts
+
+	byProject := map[string]int{}
+	for _, receipt := range receipts {
+		statement.Totals[receipt.Currency] += receipt.Amount
+
+		key := receipt.ProjectID + "|" + receipt.Currency
+		i, ok := byProject[key]
+		if !ok {
+			i = len(statement.ByProject)
+			byProject[key] = i
+			statement.ByProject = append(statement.ByProject, StatementLine{ProjectID: receipt.ProjectID, ProjectName: receipt.ProjectName, Currency: receipt.Currency, SDG: receipt.SDG})
+		}
+		statement.ByProject[i].Amount += receipt.Amount
+		statement.ByProject[i].ReceiptIDs = append(statement.ByProject[i].ReceiptIDs, receipt.ReceiptID)
+
+		for _, sdg := range receipt.SDG {
+			if statement.BySDG[sdg] == nil {
+				statement.BySDG[sdg] = map[string]float64{}
+			}
+			statement.BySDG[sdg][receipt.Currency] += receipt.Amount
+		}
+	}
+	sort.Slice(statement.ByProject, func(i, j int) bool {
+		if statement.ByProject[i].ProjectID != statement.ByProject[j].ProjectID {
+			return statement.ByProject[i].ProjectID < statement.ByProject[j].ProjectID
+		}
+		return statement.ByProject[i].Currency < statement.ByProject[j].Currency
+	})
+
+	statementAsBytes, _ := json.Marshal(statement) //convert to array of bytes
+
+	log.Println("- end - get donor statement")
+	return shim.Success(statementAsBytes)
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//dateOnlyLayout is the ISO-8601 calendar date used by most clients
+const dateOnlyLayout = "2006-01-02"
+
+//dateLayouts are the ISO-8601 forms accepted for schedule dates
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", dateOnlyLayout}
+
+//parseDate will parse an ISO-8601 date or date-time into UTC time
+func parseDate(str string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		parsedValue, err := time.Parse(layout, str)
+		if err == nil {
+			return parsedValue.UTC(), nil
+		}
+	}
+	return time.Time{}, errors.New("'" + str + "' is not an ISO-8601 date (YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ)")
+}
+
+//parseEndDate will parse an ISO-8601 end date, a plain calendar date covers the whole day
+func parseEndDate(str string) (time.Time, error) {
+	parsedValue, err := parseDate(str)
+	if err != nil {
+		return parsedValue, err
+	}
+	if _, errd := time.Parse(dateOnlyLayout, str); errd == nil {
+		parsedValue = parsedValue.Add(24*time.Hour - time.Nanosecond)
+	}
+	return parsedValue, nil
+}
+
+//validateSchedule checks that both dates are ISO-8601 and that end comes after start
+func validateSchedule(startDate string, endDate string) error {
+	start, err := parseDate(startDate)
+	if err != nil {
+		return errors.New("Invalid start date: " + err.Error())
+	}
+	end, err := parseEndDate(endDate)
+	if err != nil {
+		return errors.New("Invalid end date: " + err.Error())
+	}
+	if !end.After(start) {
+		return errors.New("End date " + endDate + " must come after start date " + startDate)
+	}
+	return nil
+}
+
+//validateWithin checks that a child schedule nests inside the schedule of its parent
+func validateWithin(startDate string, endDate string, parentStartDate string, parentEndDate string, parent string) error {
+	err := validateSchedule(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	parentStart, err := parseDate(parentStartDate)
+	if err != nil {
+		return errors.New("Invalid start date of " + parent + ": " + err.Error())
+	}
+	parentEnd, err := parseEndDate(parentEndDate)
+	if err != nil {
+		return errors.New("Invalid end date of " + parent + ": " + err.Error())
+	}
+	start, _ := parseDate(startDate)
+	end, _ := parseEndDate(endDate)
+	if start.Before(parentStart) || end.After(parentEnd) {
+		return errors.New("Dates " + startDate + " - " + endDate + " must lie within " + parent + " dates " + parentStartDate + " - " + parentEndDate)
+	}
+	return nil
+}
+
+//startsBefore orders schedule dates chronologically, dates that cannot be parsed sort last
+func startsBefore(a string, b string) bool {
+	aTime, errA := parseDate(a)
+	bTime, errB := parseDate(b)
+	if errA != nil || errB != nil {
+		return errA == nil && errB != nil
+	}
+	return aTime.Before(bTime)
+}
+
+//getTxDate returns the transaction timestamp, the only clock time based rules may use
+func getTxDate(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errors.New("failed to get transaction timestamp")
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
+}
+
+//isPastEnd tells whether the end date lies before now, dates that cannot be parsed are never overdue
+func isPastEnd(endDate string, now time.Time) bool {
+	end, err := parseEndDate(endDate)
+	if err != nil {
+		return false
+	}
+	return end.Before(now)
+}
+
+//daysOverdue is the number of whole days since the end date
+func daysOverdue(endDate string, now time.Time) int {
+	end, err := parseEndDate(endDate)
+	if err != nil {
+		return 0
+	}
+	return int(now.Sub(end).Hours() / 24)
+}
+
+//isActivityOverdue - past its end date without proof and not yet validated
+func isActivityOverdue(activity Activity, now time.Time) bool {
+	return isPastEnd(activity.EndDate, now) && activity.ProofVersion == 0 && activity.Status != "Validation Successful"
+}
+
+// ============================================================================================================================
+// findOverdue - milestones and activities of a project that are late at the given time
+//
+// A milestone is late once its end date has passed while any of its activities is still not validated.
+// ============================================================================================================================
+func findOverdue(stub shim.ChaincodeStubInterface, projectID string, now time.Time) ([]Milestone, []Activity, error) {
+	var lateMilestones []Milestone
+	var lateActivities []Activity
+
+	milestones, err := getMilestonesByProject(stub, projectID)
+	if err != nil {
+		return lateMilestones, lateActivities, err
+	}
+	activities, err := getActivitiesByProject(stub, projectID)
+	if err != nil {
+		return lateMilestones, lateActivities, err
+	}
+
+	openActivities := map[string]bool{}
+	for i := range activities {
+		if activities[i].Status != "Validation Successful" {
+			openActivities[activities[i].MilestoneID] = true
+		}
+		if isActivityOverdue(activities[i], now) {
+			lateActivities = append(lateActivities, activities[i])
+		}
+	}
+	for i := range milestones {
+		if isPastEnd(milestones[i].EndDate, now) && openActivities[milestones[i].MilestoneID] {
+			lateMilestones = append(lateMilestones, milestones[i])
+		}
+	}
+
+	return lateMilestones, lateActivities, nil
+}
+
+//projectOverdue builds the overdue report of one project
+func projectOverdue(stub shim.ChaincodeStubInterface, project Project, now time.Time) (ProjectOverdue, error) {
+	var report ProjectOverdue
+	report.ProjectID = project.ProjectID
+	report.ProjectName = project.ProjectName
+	report.Milestones = []OverdueItem{}
+	report.Activities = []OverdueItem{}
+
+	lateMilestones, lateActivities, err := findOverdue(stub, project.ProjectID, now)
+	if err != nil {
+		return report, err
+	}
+	for _, milestone := range lateMilestones {
+		report.Milestones = append(report.Milestones, OverdueItem{milestone.MilestoneID, milestone.MilestoneName, milestone.MilestoneID, milestone.EndDate, daysOverdue(milestone.EndDate, now)})
+	}
+	for _, activity := range lateActivities {
+		report.Activities = append(report.Activities, OverdueItem{activity.ActivityID, activity.ActivityName, activity.MilestoneID, activity.EndDate, daysOverdue(activity.EndDate, now)})
+	}
+	return report, nil
+}
+
+// ============================================================================================================================
+// getOverdue() - overdue milestones and activities per project or per NGO
+//
+// Inputs - Array of strings
+//        0          ,          1
+//  "project"|"ngo"  ,  projectId | organization id or name
+// ============================================================================================================================
+func getOverdue(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - get overdue")
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	now, err := getTxDate(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var projects []Project
+	if args[0] == "project" {
+		project, err := getProject(stub, args[1])
+		if err != nil {
+			fmt.Println("Project is missing " + args[1])
+			return shim.Error(err.Error())
+		}
+		projects = append(projects, project)
+	} else if args[0] == "ngo" {
+		queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"Project\",\"organization\":{\"$elemMatch\":{\"$or\":[{\"orgId\":\"%s\"},{\"OrgName\":\"%s\"}]}}}}", args[1], args[1])
+		projectsAsBytes, err := myfunction(stub, queryString)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		json.Unmarshal(projectsAsBytes, &projects)
+	} else {
+		return shim.Error("Unknown scope '" + args[0] + "'. Expecting project or ngo")
+	}
+	projects, err = filterVisibleProjects(stub, projects)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	reports := []ProjectOverdue{}
+	for i := range projects {
+		report, err := projectOverdue(stub, projects[i], now)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if len(report.Milestones) > 0 || len(report.Activities) > 0 {
+			reports = append(reports, report)
+		}
+	}
+
+	reportsAsBytes, _ := json.Marshal(reports) //convert to array of bytes
+
+	log.Println("- end - get overdue")
+	return shim.Success(reportsAsBytes)
+}
+
+// ============================================================================================================================
+// markOverdue() - sweep a project and flag late milestones and activities, anyone may call it
+//
+// Items that are no longer late get their flag cleared. An "OverdueFlagged" event lists the newly flagged items.
+//
+// Inputs - Array of strings
+//      0
+//  projectId
+// ============================================================================================================================
+func markOverdue(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - mark overdue")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	now, err := getTxDate(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// get the project
+	project, err := getProject(stub, args[0])
+	if err != nil {
+		fmt.Println("Project is missing " + args[0])
+		return shim.Error(err.Error())
+	}
+
+	lateMilestones, lateActivities, err := findOverdue(stub, project.ProjectID, now)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	late := map[string]bool{}
+	for i := range lateMilestones {
+		late[lateMilestones[i].MilestoneID] = true
+	}
+	for i := range lateActivities {
+		late[lateActivities[i].ActivityID] = true
+	}
+
+	var flagged ProjectOverdue
+	flagged.ProjectID = project.ProjectID
+	flagged.ProjectName = project.ProjectName
+	flagged.Milestones = []OverdueItem{}
+	flagged.Activities = []OverdueItem{}
+
+	milestones, err := getMilestonesByProject(stub, project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, milestone := range milestones {
+		if milestone.Overdue == late[milestone.MilestoneID] {
+			continue
+		}
+		milestone.Overdue = late[milestone.MilestoneID]
+		milestone.OverdueSince = ""
+		if milestone.Overdue {
+			milestone.OverdueSince = txTime
+			flagged.Milestones = append(flagged.Milestones, OverdueItem{milestone.MilestoneID, milestone.MilestoneName, milestone.MilestoneID, milestone.EndDate, daysOverdue(milestone.EndDate, now)})
+		}
+
+		//update milestone
+		milestone.setUpdated(callerID, txTime)
+		milestoneAsBytes, _ := json.Marshal(milestone) //convert to array of bytes
+		errm := stub.PutState(milestone.MilestoneID, milestoneAsBytes)
+		if errm != nil {
+			return shim.Error(errm.Error())
+		}
+	}
+
+	activities, err := getActivitiesByProject(stub, project.ProjectID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, activity := range activities {
+		if activity.Overdue == late[activity.ActivityID] {
+			continue
+		}
+		activity.Overdue = late[activity.ActivityID]
+		activity.OverdueSince = ""
+		if activity.Overdue {
+			activity.OverdueSince = txTime
+			flagged.Activities = append(flagged.Activities, OverdueItem{activity.ActivityID, activity.ActivityName, activity.MilestoneID, activity.EndDate, daysOverdue(activity.EndDate, now)})
+		}
+
+		//update activity
+		activity.setUpdated(callerID, txTime)
+		activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
+		erra := stub.PutState(activity.ActivityID, activityAsBytes)
+		if erra != nil {
+			return shim.Error(erra.Error())
+		}
+	}
+
+	flaggedAsBytes, _ := json.Marshal(flagged) //convert to array of bytes
+	if len(flagged.Milestones) > 0 || len(flagged.Activities) > 0 {
+		err = stub.SetEvent("OverdueFlagged", flaggedAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	log.Println("- end - mark overdue")
+	return shim.Success(flaggedAsBytes)
+}
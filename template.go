@@ -0,0 +1,562 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== CLONE AND TEMPLATE RELATED FUNCTION'S START HERE ===============================================================
+
+//structureOverrides are the project fields a clone or a project created from a template may set differently
+var structureOverrides = map[string]bool{
+	"projectName":        true,
+	"projectType":        true,
+	"description":        true,
+	"fundGoal":           true,
+	"projectBudget":      true,
+	"currency":           true,
+	"country":            true,
+	"projectLoc":         true,
+	"startDate":          true,
+	"endDate":            true,
+	"fundAllocationType": true,
+	"proofRadiusKm":      true,
+	"organization":       true, // JSON array of organization ids
+}
+
+// ============================================================================================================================
+// Get Project Template - get a project template from ledger
+// ============================================================================================================================
+func getProjectTemplate(stub shim.ChaincodeStubInterface, id string) (ProjectTemplate, error) {
+	var template ProjectTemplate
+	templateAsBytes, err := stub.GetState(id) //getState retreives a key/value from the ledger
+	if err != nil {                           //this seems to always succeed, even if key didn't exist
+		return template, errors.New("Failed to get project template - " + id)
+	}
+	unmarshalDocument(templateAsBytes, &template) //un stringify it aka JSON.parse(), migrated to the current schema
+
+	if template.ObjectType != "ProjectTemplate" || len(template.TemplateID) == 0 { //test if template is actually here or just nil
+		return template, errors.New("Project template does not exist - " + id)
+	}
+
+	return template, nil
+}
+
+//loadProjectStructure reads a project with its milestones and activities, without funds, proofs or approvals
+func loadProjectStructure(stub shim.ChaincodeStubInterface, projectID string) (ProjectTemplate, error) {
+	var structure ProjectTemplate
+	project, err := getProject(stub, projectID)
+	if err != nil {
+		return structure, err
+	}
+	milestones, err := getMilestonesByProject(stub, projectID)
+	if err != nil {
+		return structure, err
+	}
+	activities, err := getActivitiesByProject(stub, projectID)
+	if err != nil {
+		return structure, err
+	}
+	structure.Project = project
+	structure.Milestones = milestones
+	structure.Activities = activities
+	err = blankStructure(&structure)
+	return structure, err
+}
+
+//blankStructure keeps names, budgets, dates, criteria and validators and drops everything that happened since
+func blankStructure(structure *ProjectTemplate) error {
+	project := &structure.Project
+	project.FundRaised = 0
+	project.FundAllocated = 0
+	project.FundNotAllocated = 0
+	project.Donations = []string{}
+	project.BeneficiaryIDs = []string{}
+	project.IsPublished = false
+	project.IsApproved = false
+	project.ApprovalRound = 0
+	project.Remarks = ""
+	project.TransactionLoc = Location{}
+	project.Attribution = Attribution{}
+
+	for i := range structure.Milestones {
+		milestone := &structure.Milestones[i]
+		milestone.MilFundAllocated = 0
+		milestone.MilFundRequested = 0
+		milestone.MilFundReleased = 0
+		milestone.IsApproved = false
+		milestone.Overdue = false
+		milestone.OverdueSince = ""
+		milestone.TransactionLoc = Location{}
+		milestone.Attribution = Attribution{}
+	}
+
+	for i := range structure.Activities {
+		activity := &structure.Activities[i]
+		activity.FundAllocated = 0
+		activity.FundReleased = 0
+		activity.FundRequested = 0
+		activity.Validation = false
+		activity.PartialValidation = false
+		activity.IsApproved = false
+		activity.Remarks = ""
+		activity.ProofHash = ""
+		activity.ProofVersion = 0
+		activity.Overdue = false
+		activity.OverdueSince = ""
+		activity.TransactionLoc = Location{}
+		activity.Attribution = Attribution{}
+		for _, panel := range []*ValidationPanel{&activity.TechnicalValidation, &activity.FinancialValidation} {
+			if len(panel.Validators) == 0 {
+				continue
+			}
+			blank, err := newValidationPanel(panel.Validators, panel.Threshold)
+			if err != nil {
+				return errors.New("Activity " + activity.ActivityID + ": " + err.Error())
+			}
+			blank.ValidatorRole = panel.ValidatorRole
+			*panel = blank
+		}
+	}
+	return nil
+}
+
+//shiftDate moves a schedule date by the offset and keeps its format, empty dates stay empty
+func shiftDate(str string, offset time.Duration) (string, error) {
+	if len(str) == 0 || offset == 0 {
+		return str, nil
+	}
+	date, err := parseDate(str)
+	if err != nil {
+		return str, err
+	}
+	if len(str) == len(dateOnlyLayout) {
+		return date.Add(offset).Format(dateOnlyLayout), nil
+	}
+	return date.Add(offset).Format(time.RFC3339), nil
+}
+
+//applyStructureOverrides sets the overridden project fields, a new start date moves the whole schedule along
+func applyStructureOverrides(stub shim.ChaincodeStubInterface, structure *ProjectTemplate, overridesJSON string, callerID string) error {
+	if len(overridesJSON) == 0 {
+		return nil
+	}
+	var overrides map[string]json.RawMessage
+	err := json.Unmarshal([]byte(overridesJSON), &overrides)
+	if err != nil {
+		return errors.New("Expecting a JSON object of overrides")
+	}
+	for field := range overrides {
+		if !structureOverrides[field] {
+			return errors.New("Field " + field + " can not be overridden")
+		}
+	}
+
+	project := &structure.Project
+	if orgs, ok := overrides["organization"]; ok {
+		delete(overrides, "organization")
+		projOrg, ngoComp, err := parseProjectOrgs(stub, string(orgs), callerID, nil)
+		if err != nil {
+			return err
+		}
+		project.Organization = projOrg
+		project.NGOCompany = ngoComp
+	}
+
+	originalStart := project.StartDate
+	originalEnd := project.EndDate
+	remaining, _ := json.Marshal(overrides)
+	err = json.Unmarshal(remaining, project)
+	if err != nil {
+		return errors.New("Overrides do not match the project fields - " + err.Error())
+	}
+
+	if project.StartDate != originalStart {
+		newStart, err := parseDate(project.StartDate)
+		if err != nil {
+			return err
+		}
+		oldStart, err := parseDate(originalStart)
+		if err != nil {
+			return err
+		}
+		offset := newStart.Sub(oldStart)
+		if _, ok := overrides["endDate"]; !ok {
+			project.EndDate, err = shiftDate(originalEnd, offset)
+			if err != nil {
+				return err
+			}
+		}
+		for i := range structure.Milestones {
+			milestone := &structure.Milestones[i]
+			milestone.StartDate, err = shiftDate(milestone.StartDate, offset)
+			if err == nil {
+				milestone.EndDate, err = shiftDate(milestone.EndDate, offset)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for i := range structure.Activities {
+			activity := &structure.Activities[i]
+			activity.StartDate, err = shiftDate(activity.StartDate, offset)
+			if err == nil {
+				activity.EndDate, err = shiftDate(activity.EndDate, offset)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//createFromStructure stores a new project with fresh ids for the milestones and activities of the structure
+func createFromStructure(stub shim.ChaincodeStubInterface, structure ProjectTemplate, projectID string, status string, flag string, clonedFrom string, callerID string, txTime string) (Project, error) {
+	project := structure.Project
+
+	//derived ids must not be used by a document of any type
+	used, err := isKeyUsed(stub, projectID)
+	if err != nil {
+		return project, err
+	}
+	if used {
+		return project, errors.New("Id " + projectID + " is already used")
+	}
+	err = checkActivityStatus(status)
+	if err != nil {
+		return project, err
+	}
+
+	//the schedule, currency and location might have been overridden
+	err = validateSchedule(project.StartDate, project.EndDate)
+	if err != nil {
+		return project, err
+	}
+	err = checkCurrency(stub, project.Currency)
+	if err != nil {
+		return project, err
+	}
+	err = checkAllocationType(stub, project.FundAllocationType)
+	if err != nil {
+		return project, err
+	}
+	err = validateLocation(project.ProjectLoc)
+	if err != nil {
+		return project, err
+	}
+
+	//the caller owns the new project and only keeps the organizations it acts for
+	project.ProjectOwner = callerID
+	var projOrg []projectOrg
+	for _, org := range project.Organization {
+		if isOrgMember(stub, org.OrgID, callerID) {
+			projOrg = append(projOrg, org)
+		}
+	}
+	var ngoComp []ngoCompany
+	for _, org := range project.NGOCompany {
+		if isOrgMember(stub, org.OrgID, callerID) {
+			ngoComp = append(ngoComp, org)
+		}
+	}
+	project.Organization = projOrg
+	project.NGOCompany = ngoComp
+
+	project.ObjectType = "Project"
+	project.SchemaVersion = currentSchemaVersion
+	project.ProjectID = projectID
+	project.Status = status
+	project.Flag = flag
+	project.Visibility = "Just Me"
+	project.SubRole = getCallerRole(stub, callerID)
+	project.ClonedFrom = clonedFrom
+	project.setCreated(callerID, txTime)
+
+	//milestones and activities are numbered in the order of the structure
+	milestoneIDs := map[string]string{}
+	milestones := map[string]Milestone{}
+	for i, milestone := range structure.Milestones {
+		err = validateWithin(milestone.StartDate, milestone.EndDate, project.StartDate, project.EndDate, "project")
+		if err != nil {
+			return project, errors.New("Milestone " + milestone.MilestoneName + ": " + err.Error())
+		}
+		newID := projectID + "-M" + strconv.Itoa(i+1)
+		used, err = isKeyUsed(stub, newID)
+		if err != nil {
+			return project, err
+		}
+		if used {
+			return project, errors.New("Id " + newID + " is already used")
+		}
+		milestoneIDs[milestone.MilestoneID] = newID
+		milestone.ObjectType = "Milestone"
+		milestone.SchemaVersion = currentSchemaVersion
+		milestone.MilestoneID = newID
+		milestone.ProjectID = projectID
+		milestone.Status = status
+		milestone.setCreated(callerID, txTime)
+		milestones[newID] = milestone
+	}
+
+	activityCount := map[string]int{}
+	for _, activity := range structure.Activities {
+		milestoneID, ok := milestoneIDs[activity.MilestoneID]
+		if !ok {
+			return project, errors.New("Activity " + activity.ActivityName + " belongs to no milestone of the project")
+		}
+		milestone := milestones[milestoneID]
+		err = validateWithin(activity.StartDate, activity.EndDate, milestone.StartDate, milestone.EndDate, "milestone")
+		if err != nil {
+			return project, errors.New("Activity " + activity.ActivityName + ": " + err.Error())
+		}
+		activityCount[milestoneID]++
+		newID := milestoneID + "-A" + strconv.Itoa(activityCount[milestoneID])
+		used, err = isKeyUsed(stub, newID)
+		if err != nil {
+			return project, err
+		}
+		if used {
+			return project, errors.New("Id " + newID + " is already used")
+		}
+		activity.ObjectType = "Activity"
+		activity.SchemaVersion = currentSchemaVersion
+		activity.ActivityID = newID
+		activity.MilestoneID = milestoneID
+		activity.ProjectID = projectID
+		activity.Status = status
+		err = checkActivityValidators(stub, project, &activity)
+		if err != nil {
+			return project, errors.New("Activity " + activity.ActivityName + ": " + err.Error())
+		}
+		activity.setCreated(callerID, txTime)
+
+		//store activity
+		activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
+		err = stub.PutState(activity.ActivityID, activityAsBytes)
+		if err != nil {
+			return project, err
+		}
+	}
+
+	//store milestones
+	for _, milestone := range structure.Milestones {
+		stored := milestones[milestoneIDs[milestone.MilestoneID]]
+		milestoneAsBytes, _ := json.Marshal(stored) //convert to array of bytes
+		err = stub.PutState(stored.MilestoneID, milestoneAsBytes)
+		if err != nil {
+			return project, err
+		}
+	}
+
+	//store project
+	projectAsBytes, _ := json.Marshal(project) //convert to array of bytes
+	err = stub.PutState(project.ProjectID, projectAsBytes)
+	return project, err
+}
+
+// ============================================================================================================================
+// cloneProject() - copy the milestones and activities of a project into a new project
+//
+// Names, budgets, dates, criteria and validators are copied, funds, donations, beneficiaries, proofs, votes and approvals
+// are not. Milestones get the ids <projectId>-M1, -M2, ..., activities <milestoneId>-A1, -A2, ...
+//
+// Inputs - Array of strings
+//        0         ,       1      ,   2    ,  3   ,       4 (optional)
+//  sourceProjectId , newProjectId , status , flag , {"startDate":"2027-01-01","country":"Kenya",...}
+//
+// A new startDate moves every date of the schedule along unless endDate is overridden as well.
+// ============================================================================================================================
+func cloneProject(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - clone project")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 && len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 4 or 5")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkCanViewProject(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	structure, err := loadProjectStructure(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(args) == 5 {
+		err = applyStructureOverrides(stub, &structure, args[4], callerID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	projectID, err := resolveID(stub, args[1], "PRJ")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	project, err := createFromStructure(stub, structure, projectID, args[2], args[3], args[0], callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - clone project")
+	return shim.Success([]byte(project.ProjectID))
+}
+
+// ============================================================================================================================
+// addProjectTemplate() - save the structure of a project as a reusable template
+//
+// Inputs - Array of strings
+//       0     ,      1       ,        2
+//  templateId , templateName , sourceProjectId
+//
+// ============================================================================================================================
+func addProjectTemplate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - add project template")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//templates are readable by everybody, only members may publish the structure of a project
+	project, err := getProject(stub, args[2])
+	if err != nil {
+		fmt.Println("Project is missing " + args[2])
+		return shim.Error(err.Error())
+	}
+	err = checkProjectMember(stub, project, callerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// check the template
+	existing, err := stub.GetState(args[0])
+	if err != nil || len(existing) > 0 {
+		return shim.Error("Project template is already present " + args[0])
+	}
+
+	template, err := loadProjectStructure(stub, args[2])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	template.ObjectType = "ProjectTemplate"
+	template.SchemaVersion = currentSchemaVersion
+	template.TemplateID = args[0]
+	template.TemplateName = args[1]
+	template.SourceProjectID = project.ProjectID
+	template.Project.ProjectID = ""
+	template.setCreated(callerID, txTime)
+
+	//store template
+	templateAsBytes, _ := json.Marshal(template) //convert to array of bytes
+	err = stub.PutState(template.TemplateID, templateAsBytes)
+	if err != nil {
+		fmt.Println("Could not store project template")
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - add project template")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// createProjectFromTemplate() - create a project with the milestones and activities of a template
+//
+// Inputs - Array of strings
+//       0     ,       1      ,   2    ,  3   ,       4 (optional)
+//  templateId , newProjectId , status , flag , {"startDate":"2027-01-01","country":"Kenya",...}
+//
+// ============================================================================================================================
+func createProjectFromTemplate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - create project from template")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 && len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 4 or 5")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	template, err := getProjectTemplate(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(args) == 5 {
+		err = applyStructureOverrides(stub, &template, args[4], callerID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	projectID, err := resolveID(stub, args[1], "PRJ")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	project, err := createFromStructure(stub, template, projectID, args[2], args[3], template.TemplateID, callerID, txTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end - create project from template")
+	return shim.Success([]byte(project.ProjectID))
+}
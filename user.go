@@ -20,7 +20,9 @@ under the License.
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
@@ -28,15 +30,40 @@ import (
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
+//bootstrapAdminKey holds the identity allowed to use write() while the network is bootstrapped, see Init
+var bootstrapAdminKey = reservedKey("bootstrapAdmin")
+
+//adminSeededKey marks that Init has seeded the first admin, it is never removed so upgrades can't seed another one
+var adminSeededKey = reservedKey("adminSeeded")
+
+//checkBootstrapAdmin - error unless bootstrapping is open and the caller is the bootstrap admin
+func checkBootstrapAdmin(stub shim.ChaincodeStubInterface) error {
+	bootstrapAdmin, err := stub.GetState(bootstrapAdminKey)
+	if err != nil {
+		return err
+	}
+	if len(bootstrapAdmin) == 0 {
+		return errors.New("Bootstrapping is closed")
+	}
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		return err
+	}
+	if callerID != string(bootstrapAdmin) {
+		return errors.New("Only the bootstrap admin can do this")
+	}
+	return nil
+}
+
 // ============================================================================================================================
-// write() - genric write variable into ledger
+// write() - genric write variable into ledger, only for the bootstrap admin while bootstrapping is open
 //
 // Shows Off PutState() - writting a key/value into the ledger
 //
 // Inputs - Array of strings
-//    0   ,    1
-//   key  ,  value
-//  "abc" , "test"
+//    0   ,   1   ,    2
+//        ,  key  ,  value
+//        , "abc" , "test"
 // ============================================================================================================================
 func write(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var key, value string
@@ -48,7 +75,12 @@ func write(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	// input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = checkBootstrapAdmin(stub)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -64,6 +96,35 @@ func write(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	return shim.Success(nil)
 }
 
+// ============================================================================================================================
+// closeBootstrap() - the bootstrap admin disables write() for good, until the chaincode is upgraded with a new one
+//
+// Inputs - Array of strings
+//     0
+//  "close"
+// ============================================================================================================================
+func closeBootstrap(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting close bootstrap")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	err = checkBootstrapAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelState(bootstrapAdminKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end close bootstrap")
+	return shim.Success(nil)
+}
+
 // ============================================================================================================================
 // invke() - fetches JSON from mongodb and creates ASSET struct
 // ============================================================================================================================
@@ -75,98 +136,153 @@ func invke(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 //=============== USER REGISTARTION RELATED FUNCTION'S START HERE ===============================================================
 
-//addPrivateUser
-func addPrivateUser(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var err error
-	log.Println("started to register private user")
+//userCollection holds personal data of users and donors, see collections_config.json
+const userCollection = "collectionUserPrivate"
 
-	if len(args) != 6 {
-		return shim.Error("Incorrect number of arguments. Expecting 6")
+//registerPrivateUser stores a foundation user under the given id, personal details travel in the transient field "user"
+func registerPrivateUser(stub shim.ChaincodeStubInterface, id string, username string, role string, actor string, txTime string) error {
+	//check if user already exists
+	_, err := getPrivateUser(stub, id)
+	if err == nil {
+		fmt.Println("This Private user already exists - " + id)
+		return errors.New("This private user already exists - " + id)
 	}
 
-	//input sanitation
-	err = sanitize_arguments(args)
+	err = checkRoleAssignment(stub, actor, role)
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
 	}
 
-	certname, err := get_cert(stub)
+	var details PrivateUserDetails
+	err = getTransientJSON(stub, "user", &details)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
-	}
-	fmt.Println("certname = ", string(certname))
-
-	//check if user already exists
-	_, err = getPrivateUser(stub, args[0])
-	if err == nil {
-		fmt.Println("This Private user already exists - " + args[0])
-		return shim.Error("This private user already exists - " + args[0])
+		return err
 	}
 
 	var user PrivateUser
 	user.ObjectType = "PrivateUser"
-	user.UserID = args[0]
-	user.Username = args[0]
-	user.FirstName = args[1]
-	user.LastName = args[2]
-	user.Role = args[3]
-
-	var location Location
-	location.Latitude = args[4]
-	location.Longitude = args[5]
-	user.Location = location
+	user.SchemaVersion = currentSchemaVersion
+	user.UserID = id
+	user.Username = username
+	user.Role = role
 
 	log.Println("final obj of private user ", user)
 
+	//store private details
+	err = putPrivateUserDetails(stub, user.UserID, details)
+	if err != nil {
+		return err
+	}
+
 	//store user
+	user.setCreated(actor, txTime)
 	userAsBytes, _ := json.Marshal(user)
 	err = stub.PutState(user.UserID, userAsBytes)
+	return err
+}
+
+// ============================================================================================================================
+// addPrivateUser() - register the calling identity as foundation user
+//
+// Inputs - Array of strings
+//      0     ,  1
+//  username , role
+//
+// Transient - "user": personal details, kept in the private user collection
+// ============================================================================================================================
+func addPrivateUser(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("started to register private user")
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	fmt.Println("caller = ", callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = registerPrivateUser(stub, callerID, args[0], args[1], callerID, txTime)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	log.Println("- end registration of private user")
-	return shim.Success(nil)
+	return shim.Success([]byte(callerID))
 }
 
-//updatePrivateUser
+//updatePrivateUser - personal details travel in the transient field "user" and are kept in the private user collection
 func updatePrivateUser(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("started to update the private user")
 
-	if len(args) != 4 {
-		return shim.Error("Incorrect number of arguments. Expecting 4")
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
 
-	log.Println("certname ", string(certname))
+	log.Println("caller ", callerID)
 
 	//check if user already exists
-	privateUser, err := getPrivateUser(stub, string(certname))
+	privateUser, err := getPrivateUser(stub, callerID)
 	if err != nil {
-		fmt.Println("This private user is not exists - " + string(certname))
-		return shim.Error("This private user is not exists - " + string(certname))
+		fmt.Println("This private user is not exists - " + callerID)
+		return shim.Error("This private user is not exists - " + callerID)
 	}
 	log.Println("args = ", args)
 
-	privateUser.FirstName = args[0]
-	privateUser.LastName = args[1]
-	privateUser.Role = args[2]
-	privateUser.Company = args[3]
+	var details PrivateUserDetails
+	err = getTransientJSON(stub, "user", &details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if privateUser.Role != args[0] {
+		err = checkRoleAssignment(stub, callerID, args[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	privateUser.Role = args[0]
+
+	//store private details
+	err = putPrivateUserDetails(stub, privateUser.UserID, details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 
 	//store user
+	privateUser.setUpdated(callerID, txTime)
 	userAsBytes, _ := json.Marshal(privateUser)
 	err = stub.PutState(privateUser.UserID, userAsBytes)
 	if err != nil {
@@ -177,99 +293,150 @@ func updatePrivateUser(stub shim.ChaincodeStubInterface, args []string) pb.Respo
 	return shim.Success(nil)
 }
 
-//addDonor
+//registerDonor stores a donor under the given id, company details travel in the transient field "donor"
+func registerDonor(stub shim.ChaincodeStubInterface, id string, username string, role string, actor string, txTime string) error {
+	//check if user already exists
+	_, err := getDonor(stub, id)
+	if err == nil {
+		fmt.Println("This donor user already exists - " + id)
+		return errors.New("This donor user already exists - " + id)
+	}
+
+	err = checkRoleAssignment(stub, actor, role)
+	if err != nil {
+		return err
+	}
+
+	var details DonorDetails
+	err = getTransientJSON(stub, "donor", &details)
+	if err != nil {
+		return err
+	}
+
+	var user Donor
+	user.ObjectType = "Donor"
+	user.SchemaVersion = currentSchemaVersion
+	user.DonorID = id
+	user.DonorUsername = username
+	user.Role = role
+
+	log.Println("final DONOR user object ", user)
+
+	//store private details
+	err = putDonorDetails(stub, user.DonorID, details)
+	if err != nil {
+		return err
+	}
+
+	//store user
+	user.setCreated(actor, txTime)
+	userAsBytes, _ := json.Marshal(user)
+	return stub.PutState(user.DonorID, userAsBytes)
+}
+
+// ============================================================================================================================
+// addDonor() - register the calling identity as donor
+//
+// Inputs - Array of strings
+//        0       ,  1
+//  donorUsername , role
+//
+// Transient - "donor": company details, kept in the private user collection
+// ============================================================================================================================
 func addDonor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("started to register new donor")
 
-	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting 5")
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
 
-	fmt.Println("certname ", string(certname))
-
-	//check if user already exists
-	_, err = getDonor(stub, args[0])
-	if err == nil {
-		fmt.Println("This donor user already exists - " + args[0])
-		return shim.Error("This donor user already exists - " + args[0])
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
-	var user Donor
-	user.ObjectType = "Donor"
-	user.DonorID = args[0]
-	user.DonorUsername = args[0]
-	user.DonorCompany = args[1]
-	user.Role = args[2]
-
-	var location Location
-	location.Latitude = args[3]
-	location.Longitude = args[4]
-
-	log.Println("final DONOR user object ", user)
+	fmt.Println("caller ", callerID)
 
-	//store user
-	userAsBytes, _ := json.Marshal(user)
-	err = stub.PutState(user.DonorID, userAsBytes)
+	err = registerDonor(stub, callerID, args[0], args[1], callerID, txTime)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	log.Println("- end init Donor")
-	return shim.Success(nil)
+	return shim.Success([]byte(callerID))
 }
 
-//updateDonor
+//updateDonor - company details travel in the transient field "donor" and are kept in the private user collection
 func updateDonor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("started to update the donor")
 
-	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting 5")
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
 	//check if user already exists
-	donorUser, err := getDonor(stub, string(certname))
+	donorUser, err := getDonor(stub, callerID)
 	if err != nil {
-		fmt.Println("This Donor user already exists - " + string(certname))
-		return shim.Error("This Donor user already exists - " + string(certname))
+		fmt.Println("This Donor user does not exist - " + callerID)
+		return shim.Error("This Donor user does not exist - " + callerID)
 	}
 
-	donorUser.DonorUsername = args[0]
-	donorUser.DonorCompany = args[1]
-	donorUser.Role = args[2]
+	var details DonorDetails
+	err = getTransientJSON(stub, "donor", &details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 
-	var location Location
-	location.Latitude = args[3]
-	location.Longitude = args[4]
+	if donorUser.Role != args[1] {
+		err = checkRoleAssignment(stub, callerID, args[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	donorUser.DonorUsername = args[0]
+	donorUser.Role = args[1]
 
 	log.Println("final updated the donor user ", donorUser)
 
+	//store private details
+	err = putDonorDetails(stub, donorUser.DonorID, details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	//store user
+	donorUser.setUpdated(callerID, txTime)
 	userAsBytes, _ := json.Marshal(donorUser)
 	err = stub.PutState(donorUser.DonorID, userAsBytes)
 	if err != nil {
@@ -280,7 +447,49 @@ func updateDonor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	return shim.Success(nil)
 }
 
-//addOrg
+//registerOrg stores an organization under the given id
+func registerOrg(stub shim.ChaincodeStubInterface, id string, args []string, actor string, txTime string) error {
+	//check if organization already exists
+	_, err := getOrg(stub, id)
+	if err == nil {
+		fmt.Println("This organization is already exists - " + id)
+		return errors.New("This organization is already exists - " + id)
+	}
+
+	err = checkRoleAssignment(stub, actor, args[2])
+	if err != nil {
+		return err
+	}
+
+	var user Organization
+	user.ObjectType = "Organization"
+	user.SchemaVersion = currentSchemaVersion
+	user.OrgID = id
+	user.OrgUsername = args[0]
+	user.OrgCompany = args[1]
+	user.Role = args[2]
+
+	location, err := parseLocation(args[3], args[4])
+	if err != nil {
+		return err
+	}
+	user.Location = location
+
+	log.Println("final organization object ", user)
+
+	//store user
+	user.setCreated(actor, txTime)
+	userAsBytes, _ := json.Marshal(user)
+	return stub.PutState(user.OrgID, userAsBytes)
+}
+
+// ============================================================================================================================
+// addOrg() - register the calling identity as organization
+//
+// Inputs - Array of strings
+//       0      ,     1      ,  2   ,    3     ,     4
+//  orgUsername , orgCompany , role , latitude , longitude
+// ============================================================================================================================
 func addOrg(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	log.Println("started to add new organization")
@@ -290,47 +499,30 @@ func addOrg(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
 	}
-	fmt.Println("certname ", string(certname))
+	fmt.Println("caller ", callerID)
 
-	//check if organization already exists
-	_, err = getOrg(stub, args[0])
-	if err == nil {
-		fmt.Println("This organization is already exists - " + args[0])
-		return shim.Error("This organization is already exists - " + args[0])
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
-	var user Organization
-	user.ObjectType = "Organization"
-	user.OrgID = args[0]
-	user.OrgUsername = args[0]
-	user.OrgCompany = args[1]
-	user.Role = args[2]
-
-	var location Location
-	location.Latitude = args[3]
-	location.Longitude = args[4]
-
-	log.Println("final organization object ", user)
-
-	//store user
-	userAsBytes, _ := json.Marshal(user)
-	err = stub.PutState(user.OrgID, userAsBytes)
+	err = registerOrg(stub, callerID, args, callerID, txTime)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	log.Println("- end registration of nre organization")
-	return shim.Success(nil)
+	return shim.Success([]byte(callerID))
 }
 
 //updateOrg
@@ -343,35 +535,49 @@ func updateOrg(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	err = sanitize_arguments(stub, args)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	certname, err := get_cert(stub)
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
 	if err != nil {
-		fmt.Printf("INVOKE: Error retrieving cert: %s", err)
-		return shim.Error("Error retrieving cert")
+		return shim.Error(err.Error())
 	}
 
 	//check if organization already exists
-	orgUser, err := getOrg(stub, string(certname))
+	orgUser, err := getOrg(stub, callerID)
 	if err != nil {
-		fmt.Println("This Donor user already exists - " + string(certname))
-		return shim.Error("This Donor user already exists - " + string(certname))
+		fmt.Println("This organization does not exist - " + callerID)
+		return shim.Error("This organization does not exist - " + callerID)
 	}
 
+	if orgUser.Role != args[2] {
+		err = checkRoleAssignment(stub, callerID, args[2])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
 	orgUser.OrgUsername = args[0]
 	orgUser.OrgCompany = args[1]
 	orgUser.Role = args[2]
 
-	var location Location
-	location.Latitude = args[3]
-	location.Longitude = args[4]
+	location, err := parseLocation(args[3], args[4])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	orgUser.Location = location
 
 	log.Println("final organization object ", orgUser)
 
 	//store user
+	orgUser.setUpdated(callerID, txTime)
 	userAsBytes, _ := json.Marshal(orgUser)
 	err = stub.PutState(orgUser.OrgID, userAsBytes)
 	if err != nil {
@@ -381,3 +587,288 @@ func updateOrg(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	log.Println("- end registration of nre organization")
 	return shim.Success(nil)
 }
+
+//=============== ADMIN REGISTRATION RELATED FUNCTION'S START HERE ===============================================================
+
+//privilegedRoles can only be handed out by admins, every other role is chosen by the users themselves
+var privilegedRoles = map[string]bool{
+	"admin":     true,
+	"reviewer":  true,
+	"validator": true,
+	"registrar": true,
+}
+
+//isAdmin tells whether the id belongs to a foundation user with the admin role
+func isAdmin(stub shim.ChaincodeStubInterface, id string) bool {
+	user, err := getPrivateUser(stub, id)
+	return err == nil && user.Role == "admin"
+}
+
+//isCallerAdmin - besides registered admins, identities of an admin MSP enrolled with the attribute admin=true count as admins
+func isCallerAdmin(stub shim.ChaincodeStubInterface, callerID string) bool {
+	if isAdmin(stub, callerID) {
+		return true
+	}
+	value, err := getCallerAttribute(stub, "admin")
+	return err == nil && value == "true" && isAdminMSP(stub)
+}
+
+//checkRoleAssignment - only admins hand out privileged roles, the first admin is seeded by Init
+func checkRoleAssignment(stub shim.ChaincodeStubInterface, actor string, role string) error {
+	if !privilegedRoles[role] || isCallerAdmin(stub, actor) {
+		return nil
+	}
+	return errors.New("Only admins can assign the role " + role)
+}
+
+//seedAdmin registers the bootstrap admin named in Init as admin, an existing foundation user is promoted
+func seedAdmin(stub shim.ChaincodeStubInterface, id string, txTime string) error {
+	err := checkClientID(id)
+	if err != nil {
+		return err
+	}
+	user, err := getPrivateUser(stub, id)
+	if err != nil {
+		user.ObjectType = "PrivateUser"
+		user.SchemaVersion = currentSchemaVersion
+		user.UserID = id
+		user.Username = id
+		user.setCreated("init", txTime)
+	}
+	if _, err := getDonor(stub, id); err == nil {
+		return errors.New("Bootstrap admin " + id + " is registered as donor")
+	}
+	if _, err := getOrg(stub, id); err == nil {
+		return errors.New("Bootstrap admin " + id + " is registered as organization")
+	}
+	user.Role = "admin"
+	user.setUpdated("init", txTime)
+	userAsBytes, _ := json.Marshal(user)
+	return stub.PutState(user.UserID, userAsBytes)
+}
+
+// ============================================================================================================================
+// adminRegisterUser() - an admin registers a user, donor or organization on behalf of another identity
+//
+// Inputs - Array of strings
+//            0             ,   1   ,   2...
+//  "user"|"donor"|"org"    ,  id   ,  the arguments of addPrivateUser, addDonor or addOrg
+//
+// Transient - "user" or "donor" as for the self registration
+// ============================================================================================================================
+func adminRegisterUser(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("started admin registration")
+
+	if len(args) < 2 {
+		return shim.Error("Incorrect number of arguments. Expecting at least 2")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if !isCallerAdmin(stub, callerID) {
+		return shim.Error("Only admins can register other identities")
+	}
+
+	kind, id, rest := args[0], args[1], args[2:]
+	if kind == "user" {
+		if len(rest) != 2 {
+			return shim.Error("Incorrect number of arguments. Expecting 4")
+		}
+		err = registerPrivateUser(stub, id, rest[0], rest[1], callerID, txTime)
+	} else if kind == "donor" {
+		if len(rest) != 2 {
+			return shim.Error("Incorrect number of arguments. Expecting 4")
+		}
+		err = registerDonor(stub, id, rest[0], rest[1], callerID, txTime)
+	} else if kind == "org" {
+		if len(rest) != 5 {
+			return shim.Error("Incorrect number of arguments. Expecting 7")
+		}
+		err = registerOrg(stub, id, rest, callerID, txTime)
+	} else {
+		return shim.Error("Unknown kind '" + kind + "'. Expecting user, donor or org")
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	log.Println("- end admin registration")
+	return shim.Success(nil)
+}
+
+//=============== PRIVATE USER DATA RELATED FUNCTION'S START HERE ===============================================================
+
+//putPrivateUserDetails stores the personal details of a user in the private user collection
+func putPrivateUserDetails(stub shim.ChaincodeStubInterface, id string, details PrivateUserDetails) error {
+	err := validateLocation(details.Location)
+	if err != nil {
+		return err
+	}
+	details.ObjectType = "PrivateUserDetails"
+	details.SchemaVersion = currentSchemaVersion
+	details.UserID = id
+	detailsAsBytes, _ := json.Marshal(details)
+	return stub.PutPrivateData(userCollection, id, detailsAsBytes)
+}
+
+//putDonorDetails stores the company details of a donor in the private user collection
+func putDonorDetails(stub shim.ChaincodeStubInterface, id string, details DonorDetails) error {
+	err := validateLocation(details.Location)
+	if err != nil {
+		return err
+	}
+	details.ObjectType = "DonorDetails"
+	details.SchemaVersion = currentSchemaVersion
+	details.DonorID = id
+	detailsAsBytes, _ := json.Marshal(details)
+	return stub.PutPrivateData(userCollection, donorDetailsKey(id), detailsAsBytes)
+}
+
+//donorDetailsKey keeps donor and private user details of the same id apart
+func donorDetailsKey(id string) string {
+	return "donor~" + id
+}
+
+// ============================================================================================================================
+// getUserDetails() - read the private details of the calling user, members of the collection only
+//
+// Inputs - Array of strings
+//    0
+//   id
+// ============================================================================================================================
+func getUserDetails(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("started to read private user details")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	if callerID != args[0] {
+		return shim.Error("Private details can only be read by their owner")
+	}
+
+	userAsBytes, err := stub.GetPrivateData(userCollection, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	donorAsBytes, err := stub.GetPrivateData(userCollection, donorDetailsKey(args[0]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("{\"user\":")
+	if len(userAsBytes) == 0 {
+		buffer.WriteString("null")
+	} else {
+		buffer.Write(userAsBytes)
+	}
+	buffer.WriteString(",\"donor\":")
+	if len(donorAsBytes) == 0 {
+		buffer.WriteString("null")
+	} else {
+		buffer.Write(donorAsBytes)
+	}
+	buffer.WriteString("}")
+
+	log.Println("- end read private user details")
+	return shim.Success(buffer.Bytes())
+}
+
+// ============================================================================================================================
+// purgePrivateUser() - erase the private part of a user or donor (right to erasure), the public handle stays
+//
+// The user or donor can purge their own details, admins purge them on an erasure request.
+//
+// Inputs - Array of strings
+//    0
+//   id
+// ============================================================================================================================
+func purgePrivateUser(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("started to purge private user data")
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//admins handle erasure requests of users who can no longer call themselves
+	if callerID != args[0] && !isCallerAdmin(stub, callerID) {
+		return shim.Error("Private data can only be purged by its owner or an admin")
+	}
+
+	err = stub.DelPrivateData(userCollection, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.DelPrivateData(userCollection, donorDetailsKey(args[0]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	//rewriting the public records drops personal fields stored by earlier versions
+	if privateUser, err := getPrivateUser(stub, args[0]); err == nil {
+		privateUser.setUpdated(callerID, txTime)
+		userAsBytes, _ := json.Marshal(privateUser)
+		err = stub.PutState(privateUser.UserID, userAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	if donorUser, err := getDonor(stub, args[0]); err == nil {
+		donorUser.setUpdated(callerID, txTime)
+		userAsBytes, _ := json.Marshal(donorUser)
+		err = stub.PutState(donorUser.DonorID, userAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	log.Println("- end purge private user data")
+	return shim.Success(nil)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//=============== VALIDATION PANEL RELATED FUNCTION'S START HERE ===============================================================
+
+//newValidationPanel builds an M-of-N panel, duplicate validators are dropped
+func newValidationPanel(validators []string, threshold int) (ValidationPanel, error) {
+	var panel ValidationPanel
+	seen := map[string]bool{}
+	for i := range validators {
+		if len(validators[i]) == 0 || seen[validators[i]] {
+			continue
+		}
+		seen[validators[i]] = true
+		panel.Validators = append(panel.Validators, validators[i])
+	}
+	if len(panel.Validators) == 0 {
+		return panel, errors.New("validation panel needs at least one validator")
+	}
+	if threshold < 1 || threshold > len(panel.Validators) {
+		return panel, errors.New("validation threshold must be between 1 and " + strconv.Itoa(len(panel.Validators)))
+	}
+	panel.Threshold = threshold
+	panel.Votes = []ValidationVote{}
+	panel.Outcome = "Pending"
+	return panel, nil
+}
+
+//isValidator tells whether the id sits on the panel
+func (p *ValidationPanel) isValidator(id string) bool {
+	for i := range p.Validators {
+		if p.Validators[i] == id {
+			return true
+		}
+	}
+	return false
+}
+
+//castVote records one validator's verdict and recomputes the outcome
+func (p *ValidationPanel) castVote(validator string, approve bool, remarks string, evidence []string, proofVersion int, txTime string) error {
+	if !p.isValidator(validator) {
+		return errors.New(validator + " is not a validator of this activity")
+	}
+	if p.Outcome == "Passed" || p.Outcome == "Failed" {
+		return errors.New("validation is already decided - " + p.Outcome)
+	}
+	for i := range p.Votes {
+		if p.Votes[i].Validator == validator {
+			return errors.New(validator + " has already voted on this activity")
+		}
+	}
+
+	var vote ValidationVote
+	vote.Validator = validator
+	vote.Verdict = "Rejected"
+	if approve {
+		vote.Verdict = "Approved"
+	}
+	vote.Remarks = remarks
+	vote.Evidence = evidence
+	vote.ProofVersion = proofVersion
+	vote.VotedAt = txTime
+	p.Votes = append(p.Votes, vote)
+	p.tally()
+	return nil
+}
+
+//approvals counts the approving votes
+func (p *ValidationPanel) approvals() int {
+	count := 0
+	for i := range p.Votes {
+		if p.Votes[i].Verdict == "Approved" {
+			count++
+		}
+	}
+	return count
+}
+
+//tally - passed once the threshold is met, failed once it can no longer be met
+func (p *ValidationPanel) tally() {
+	approvals := p.approvals()
+	rejections := len(p.Votes) - approvals
+	if approvals >= p.Threshold {
+		p.Outcome = "Passed"
+	} else if rejections > len(p.Validators)-p.Threshold {
+		p.Outcome = "Failed"
+	} else {
+		p.Outcome = "Pending"
+	}
+}
+
+//seedValidatorPanel gives activities that only carry a single ValidatorID a one member panel on both tracks
+func seedValidatorPanel(activity *Activity) error {
+	if len(activity.ValidatorID) == 0 {
+		return nil
+	}
+	for _, panel := range []*ValidationPanel{&activity.TechnicalValidation, &activity.FinancialValidation} {
+		if len(panel.Validators) > 0 {
+			continue
+		}
+		seeded, err := newValidationPanel([]string{activity.ValidatorID}, 1)
+		if err != nil {
+			return err
+		}
+		*panel = seeded
+	}
+	return nil
+}
+
+//validationTrack picks the technical or the financial panel of an activity
+func validationTrack(activity *Activity, track string) (*ValidationPanel, error) {
+	if track == "technical" {
+		return &activity.TechnicalValidation, nil
+	} else if track == "financial" {
+		return &activity.FinancialValidation, nil
+	}
+	return nil, errors.New("Unknown validation track '" + track + "'. Expecting technical or financial")
+}
+
+//validationStatus - an activity is only validated once both tracks have passed
+func validationStatus(activity Activity) string {
+	technical := activity.TechnicalValidation.Outcome
+	financial := activity.FinancialValidation.Outcome
+	if technical == "Passed" && financial == "Passed" {
+		return "Validation Successful"
+	} else if technical == "Failed" || financial == "Failed" {
+		return "Validation failed"
+	} else if technical == "Passed" {
+		return "Technical Validation Successful"
+	} else if financial == "Passed" {
+		return "Financial Validation Successful"
+	}
+	return "Partial Validation Successful"
+}
+
+//defaultValidatorRole is asked of validators of panels that name no validator role
+const defaultValidatorRole = "validator"
+
+//validationStatuses are derived from the outcome of the validator panels by validationStatus
+var validationStatuses = map[string]bool{
+	"Validation Successful":           true,
+	"Validation failed":               true,
+	"Technical Validation Successful": true,
+	"Financial Validation Successful": true,
+	"Partial Validation Successful":   true,
+}
+
+//checkActivityStatus rejects the validation statuses, only the votes of the validators set them
+func checkActivityStatus(status string) error {
+	if validationStatuses[status] {
+		return errors.New("Activity status " + status + " is set by the validators, use updateActivityValidation or voteActivityValidation")
+	}
+	return nil
+}
+
+//checkValidatorRole makes sure the caller holds the role the track asks for
+func checkValidatorRole(stub shim.ChaincodeStubInterface, panel *ValidationPanel, validator string) error {
+	role := panel.ValidatorRole
+	if len(role) == 0 {
+		role = defaultValidatorRole
+	}
+	if !strings.EqualFold(getCallerRole(stub, validator), role) {
+		return errors.New(validator + " does not hold the validator role " + role)
+	}
+	return nil
+}
+
+//checkPanelValidators - validators hold a role only admins can grant and do not validate their own project
+func checkPanelValidators(stub shim.ChaincodeStubInterface, project Project, panel *ValidationPanel) error {
+	if len(panel.ValidatorRole) > 0 && !privilegedRoles[panel.ValidatorRole] {
+		return errors.New("Validator role " + panel.ValidatorRole + " can be chosen by anybody, expecting a role granted by admins")
+	}
+	for _, validator := range panel.Validators {
+		err := checkValidatorRole(stub, panel, validator)
+		if err != nil {
+			return err
+		}
+		if checkProjectMember(stub, project, validator) == nil {
+			return errors.New(validator + " is a member of project " + project.ProjectID + " and can not validate it")
+		}
+	}
+	return nil
+}
+
+//checkActivityValidators checks the validators of both tracks of an activity
+func checkActivityValidators(stub shim.ChaincodeStubInterface, project Project, activity *Activity) error {
+	for _, panel := range []*ValidationPanel{&activity.TechnicalValidation, &activity.FinancialValidation} {
+		err := checkPanelValidators(stub, project, panel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================================================================================================
+// setValidatorPanel() - assign an M-of-N validator panel to one validation track, only before its first vote
+//
+// Inputs - Array of strings
+//       0      ,           1            ,             2               ,     3     ,   4 (optional)
+//  activityId  , technical|financial    ,  ["validator1","validator2"] , threshold , validator role
+//
+// A threshold of "default" takes the validatorThreshold of the configuration, capped at the number of validators.
+// Only members of the project and admins can set a panel. Validators have to hold the validator role (the role named
+// in argument 4 instead, which has to be one only admins grant) and must not be members of the project.
+// ============================================================================================================================
+func setValidatorPanel(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	log.Println("starting - set validator panel")
+
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving caller identity: %s", err)
+		return shim.Error("Error retrieving caller identity")
+	}
+	log.Println(callerID)
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(args) != 4 && len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 4 or 5")
+	}
+
+	//input sanitation
+	err = sanitize_arguments(stub, args)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// check the activity
+	activity, err := getActivity(stub, args[0])
+	if err != nil {
+		fmt.Println("ActivityID is not present " + args[0])
+		return shim.Error(err.Error())
+	}
+
+	// get the project
+	project, err := getProject(stub, activity.ProjectID)
+	if err != nil {
+		fmt.Println("Project is missing " + activity.ProjectID)
+		return shim.Error(err.Error())
+	}
+	if !isCallerAdmin(stub, callerID) {
+		err = checkProjectMember(stub, project, callerID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	track, err := validationTrack(&activity, args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(track.Votes) > 0 {
+		return shim.Error(args[1] + " validation of activity " + activity.ActivityID + " has already started, the panel can not be changed")
+	}
+
+	var validators []string
+	err = json.Unmarshal([]byte(args[2]), &validators)
+	if err != nil {
+		return shim.Error("Expecting a JSON array of validators")
+	}
+	var threshold int
+	if args[3] == "default" {
+		config, err := getConfig(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		threshold = config.ValidatorThreshold
+		if threshold > len(validators) {
+			threshold = len(validators)
+		}
+	} else {
+		threshold, err = strconv.Atoi(args[3])
+		if err != nil {
+			return shim.Error("Expecting a numeric threshold")
+		}
+	}
+	panel, err := newValidationPanel(validators, threshold)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(args) == 5 {
+		panel.ValidatorRole = args[4]
+	}
+	err = checkPanelValidators(stub, project, &panel)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	*track = panel
+
+	//update activity
+	activity.setUpdated(callerID, txTime)
+	activityAsBytes, _ := json.Marshal(activity) //convert to array of bytes
+	erra := stub.PutState(activity.ActivityID, activityAsBytes)
+	if erra != nil {
+		fmt.Println("Could not update activity")
+		return shim.Error(erra.Error())
+	}
+
+	log.Println("- end - set validator panel")
+	return shim.Success(nil)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//=============== VISIBILITY RELATED FUNCTION'S START HERE ====================================================================
+
+//visibility levels of a project, anything else is treated as "Just Me"
+const (
+	visibilityJustMe       = "Just Me"      // owner and creator of the project
+	visibilityOrganization = "Organization" // plus the members of the organizations listed on the project
+	visibilityDonors       = "Donors"       // plus every registered donor
+	visibilityPublic       = "Public"       // everybody
+)
+
+//validateVisibility accepts the defined visibility levels only
+func validateVisibility(visibility string) error {
+	if visibility == visibilityJustMe || visibility == visibilityOrganization || visibility == visibilityDonors || visibility == visibilityPublic {
+		return nil
+	}
+	return errors.New("Unknown visibility '" + visibility + "'. Expecting Just Me, Organization, Donors or Public")
+}
+
+//canView tells whether the caller may read the project and everything that belongs to it,
+//admins and foundation reviewers see every project
+func canView(stub shim.ChaincodeStubInterface, project Project, callerID string) bool {
+	if project.Visibility == visibilityPublic {
+		return true
+	}
+	if callerID == project.ProjectOwner || callerID == project.CreatedBy || isCallerAdmin(stub, callerID) || isReviewer(stub, callerID) {
+		return true
+	}
+	if project.Visibility == visibilityOrganization || project.Visibility == visibilityDonors {
+		for _, org := range project.Organization {
+			if len(org.OrgID) > 0 && isOrgMember(stub, org.OrgID, callerID) {
+				return true
+			}
+		}
+	}
+	if project.Visibility == visibilityDonors {
+		if _, err := getDonor(stub, callerID); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+//checkCanView - error unless the caller may read the project
+func checkCanView(stub shim.ChaincodeStubInterface, project Project) error {
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		return err
+	}
+	if !canView(stub, project, callerID) {
+		return errors.New("project does not exist - " + project.ProjectID)
+	}
+	return nil
+}
+
+//checkCanViewProject - error unless the project exists and the caller may read it
+func checkCanViewProject(stub shim.ChaincodeStubInterface, projectID string) error {
+	project, err := getProject(stub, projectID)
+	if err != nil {
+		return err
+	}
+	return checkCanView(stub, project)
+}
+
+//filterVisibleProjects drops the projects the caller may not read
+func filterVisibleProjects(stub shim.ChaincodeStubInterface, projects []Project) ([]Project, error) {
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		return nil, err
+	}
+	visible := []Project{}
+	for i := range projects {
+		if canView(stub, projects[i], callerID) {
+			visible = append(visible, projects[i])
+		}
+	}
+	return visible, nil
+}
+
+//projectDocTypes belong to a project, whoever may read the project may read them
+var projectDocTypes = map[string]bool{
+	"Project":         true,
+	"Milestone":       true,
+	"Activity":        true,
+	"Proof":           true,
+	"Donation":        true,
+	"Receipt":         true,
+	"ApprovalRequest": true,
+	"ChangeRequest":   true,
+}
+
+//sharedDocTypes belong to no project and are readable by everybody
+var sharedDocTypes = map[string]bool{
+	"PrivateUser":     true,
+	"Donor":           true,
+	"Organization":    true,
+	"Membership":      true,
+	"Beneficiary":     true,
+	"ProjectTemplate": true,
+	"Config":          true,
+}
+
+//recordVisibility decides on generic ledger documents by their type and the project they belong to,
+//documents of any other type are readable by admins only
+type recordVisibility struct {
+	stub     shim.ChaincodeStubInterface
+	callerID string
+	admin    bool
+	projects map[string]bool
+}
+
+func newRecordVisibility(stub shim.ChaincodeStubInterface) (*recordVisibility, error) {
+	callerID, err := getCallerID(stub)
+	if err != nil {
+		return nil, err
+	}
+	return &recordVisibility{stub: stub, callerID: callerID, admin: isCallerAdmin(stub, callerID), projects: map[string]bool{}}, nil
+}
+
+func (v *recordVisibility) canViewRecord(value []byte) bool {
+	var record struct {
+		ObjectType string `json:"docType"`
+		ProjectID  string `json:"projectId"`
+	}
+	if json.Unmarshal(value, &record) != nil {
+		return v.admin
+	}
+	if sharedDocTypes[record.ObjectType] {
+		return true
+	}
+	if !projectDocTypes[record.ObjectType] || len(record.ProjectID) == 0 {
+		return v.admin
+	}
+	visible, ok := v.projects[record.ProjectID]
+	if !ok {
+		project, err := getProject(v.stub, record.ProjectID)
+		if err != nil {
+			//deleted projects stay readable for admins only
+			visible = v.admin
+		} else {
+			visible = canView(v.stub, project, v.callerID)
+		}
+		v.projects[record.ProjectID] = visible
+	}
+	return visible
+}
+
+//filterVisibleResults drops the records the caller may not read from a JSON array of {"Key":...,"Record":...}
+func filterVisibleResults(stub shim.ChaincodeStubInterface, resultsAsBytes []byte) ([]byte, error) {
+	var results []struct {
+		Key    string          `json:"Key"`
+		Record json.RawMessage `json:"Record"`
+	}
+	err := json.Unmarshal(resultsAsBytes, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return []byte("[]"), nil
+	}
+	visibility, err := newRecordVisibility(stub)
+	if err != nil {
+		return nil, err
+	}
+	visible := results[:0]
+	for _, result := range results {
+		if visibility.canViewRecord(result.Record) {
+			visible = append(visible, result)
+		}
+	}
+	return json.Marshal(visible)
+}